@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+	"github.com/theaaronruss/nes-emulator/internal/audio"
+)
+
+// speakerStreamer feeds the emulator's audio buffer to the speaker, which
+// expects stereo samples
+type speakerStreamer struct {
+	buffer  *audio.RingBuffer
+	scratch []float32
+}
+
+func (streamer *speakerStreamer) Stream(samples [][2]float64) (int, bool) {
+	if len(streamer.scratch) < len(samples) {
+		streamer.scratch = make([]float32, len(samples))
+	}
+	mono := streamer.scratch[:len(samples)]
+	streamer.buffer.Read(mono)
+	for i, sample := range mono {
+		samples[i][0] = float64(sample)
+		samples[i][1] = float64(sample)
+	}
+	return len(samples), true
+}
+
+func (streamer *speakerStreamer) Err() error {
+	return nil
+}
+
+func startAudio(sampleRate int) (*audio.RingBuffer, error) {
+	rate := beep.SampleRate(sampleRate)
+	err := speaker.Init(rate, rate.N(time.Second/30))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio device: %w", err)
+	}
+
+	buffer := audio.NewRingBuffer(sampleRate, rate.N(time.Second/10))
+	speaker.Play(&speakerStreamer{buffer: buffer})
+	return buffer, nil
+}
+
+// recording is a file the audio is recorded to, finished by Close
+type recording interface {
+	SampleRate() int
+	WriteSamples(samples []float32)
+	Close() error
+}
+
+// openRecording writes raw pcm for a .raw or .pcm path and a wav file for
+// anything else
+func openRecording(filePath string, sampleRate int) (recording, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if ext == ".raw" || ext == ".pcm" {
+		return audio.NewRawFile(filePath, sampleRate)
+	}
+	return audio.NewWavFile(filePath, sampleRate)
+}
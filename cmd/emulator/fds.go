@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+	"github.com/theaaronruss/nes-emulator/internal/nes"
+)
+
+func isFdsFile(filePath string) bool {
+	return strings.ToLower(filepath.Ext(filePath)) == ".fds"
+}
+
+// diskDrive ejects the disk with the E key and inserts the next side when it
+// is pressed again. the window title shows which side is in the drive
+type diskDrive struct {
+	cartridge *nes.Cartridge
+	lastSide  int
+}
+
+func newDiskDrive(cartridge *nes.Cartridge) *diskDrive {
+	return &diskDrive{cartridge: cartridge}
+}
+
+func (drive *diskDrive) handleHotkeys(window *opengl.Window) {
+	if !window.JustPressed(pixel.KeyE) {
+		return
+	}
+
+	if side := drive.cartridge.DiskSide(); side >= 0 {
+		drive.lastSide = side
+		drive.cartridge.EjectDisk()
+		window.SetTitle(windowTitle + " - disk ejected")
+		return
+	}
+
+	side := (drive.lastSide + 1) % drive.cartridge.DiskSideCount()
+	drive.cartridge.InsertDisk(side)
+	window.SetTitle(fmt.Sprintf("%s - disk %d side %c", windowTitle, side/2+1, 'A'+side%2))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+	"github.com/theaaronruss/nes-emulator/internal/nes"
+)
+
+const windowTitle = "NES Emulator"
+
+var channelKeys = [nes.AudioChannelCount]pixel.Button{
+	nes.ChannelPulse1:    pixel.Key1,
+	nes.ChannelPulse2:    pixel.Key2,
+	nes.ChannelTriangle:  pixel.Key3,
+	nes.ChannelNoise:     pixel.Key4,
+	nes.ChannelDmc:       pixel.Key5,
+	nes.ChannelExpansion: pixel.Key6,
+}
+
+// handleChannelHotkeys toggles muting a channel with its number key, or
+// soloing it when shift is held. 0 unmutes and unsolos everything
+func handleChannelHotkeys(window *opengl.Window, system *nes.System) {
+	changed := false
+	shift := window.Pressed(pixel.KeyLeftShift) || window.Pressed(pixel.KeyRightShift)
+	for channel, key := range channelKeys {
+		if !window.JustPressed(key) {
+			continue
+		}
+		channel := nes.AudioChannel(channel)
+		if shift {
+			system.SetChannelSolo(channel, !system.ChannelSolo(channel))
+		} else {
+			system.SetChannelMuted(channel, !system.ChannelMuted(channel))
+		}
+		changed = true
+	}
+
+	if window.JustPressed(pixel.Key0) {
+		for channel := range nes.AudioChannelCount {
+			system.SetChannelMuted(channel, false)
+			system.SetChannelSolo(channel, false)
+		}
+		changed = true
+	}
+
+	if changed {
+		window.SetTitle(channelStatusTitle(system))
+	}
+}
+
+func channelStatusTitle(system *nes.System) string {
+	var muted []string
+	var soloed []string
+	for channel := range nes.AudioChannelCount {
+		if system.ChannelMuted(channel) {
+			muted = append(muted, channel.String())
+		}
+		if system.ChannelSolo(channel) {
+			soloed = append(soloed, channel.String())
+		}
+	}
+
+	title := windowTitle
+	if len(soloed) > 0 {
+		title += " - solo: " + strings.Join(soloed, ", ")
+	}
+	if len(muted) > 0 {
+		title += " - muted: " + strings.Join(muted, ", ")
+	}
+	return title
+}
@@ -1,26 +1,38 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
-	"time"
 
 	"github.com/gopxl/pixel/v2"
 	"github.com/gopxl/pixel/v2/backends/opengl"
 	"github.com/theaaronruss/nes-emulator/internal/nes"
 )
 
+// how often the game's save file is written while it runs
+const saveIntervalFrames = 5 * 60
+
 func run() {
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: emulator <rom_file>")
+	sampleRate := flag.Int("sample-rate", 44100, "audio output sample rate in Hz")
+	noAudio := flag.Bool("no-audio", false, "disable audio output")
+	recordAudio := flag.String("record-audio", "", "record audio to the given wav file, or raw pcm for a .raw or .pcm file")
+	fdsBios := flag.String("fds-bios", "disksys.rom", "famicom disk system bios used for fds images")
+	flag.Usage = func() {
+		fmt.Println("Usage: emulator [flags] <rom_file|nsf_file|fds_file>")
 		fmt.Println("Example: emulator donkeykong.nes")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *sampleRate <= 0 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	romFile := os.Args[1]
+	romFile := flag.Arg(0)
 
 	windowConfig := opengl.WindowConfig{
-		Title:  "NES Emulator",
+		Title:  windowTitle,
 		Bounds: pixel.R(0, 0, nes.FrameWidth*2, nes.FrameHeight*2),
 		VSync:  false,
 	}
@@ -29,21 +41,95 @@ func run() {
 		panic(err)
 	}
 
-	cartridge, err := nes.NewCartridge(romFile)
-	if err != nil {
-		panic(err.Error())
+	var system *nes.System
+	var cartridge *nes.Cartridge
+	var overlay *nsfOverlay
+	var drive *diskDrive
+	switch {
+	case isNsfFile(romFile):
+		nsf, err := nes.NewNsf(romFile)
+		if err != nil {
+			panic(err.Error())
+		}
+		system = nes.NewNsfSystem(window, nsf)
+		overlay = newNsfOverlay(nsf)
+	case isFdsFile(romFile):
+		cartridge, err = nes.NewFdsCartridge(romFile, *fdsBios)
+		if err != nil {
+			panic(err.Error())
+		}
+		system = nes.NewSystem(window, cartridge)
+		drive = newDiskDrive(cartridge)
+	default:
+		cartridge, err = nes.NewCartridge(romFile)
+		if err != nil {
+			panic(err.Error())
+		}
+		system = nes.NewSystem(window, cartridge)
+	}
+	if cartridge != nil {
+		defer func() {
+			err := cartridge.Save()
+			if err != nil {
+				fmt.Println(err.Error())
+			}
+		}()
+	}
+
+	var pacer framePacer = newClockPacer()
+	if !*noAudio {
+		audioBuffer, err := startAudio(*sampleRate)
+		if err != nil {
+			fmt.Println(err.Error())
+			fmt.Println("Continuing without audio")
+		} else {
+			system.SetAudioSink(audioBuffer)
+			pacer = newAudioPacer(system, audioBuffer)
+		}
+	}
+
+	if *recordAudio != "" {
+		record, err := openRecording(*recordAudio, *sampleRate)
+		if err != nil {
+			panic(err.Error())
+		}
+		defer func() {
+			err := record.Close()
+			if err != nil {
+				fmt.Println(err.Error())
+			}
+		}()
+		system.SetRecordSink(record)
 	}
-	system := nes.NewSystem(window, cartridge)
 
 	canvas := opengl.NewCanvas(pixel.R(0, 0, nes.FrameWidth, nes.FrameHeight))
 
+	frames := 0
+	halted := false
 	for !window.Closed() {
-		start := time.Now()
+		handleChannelHotkeys(window, system)
+		if overlay != nil {
+			overlay.handleHotkeys(window, system)
+		}
+		if drive != nil {
+			drive.handleHotkeys(window)
+		}
+		pacer.waitForNextFrame()
 		system.ClockFrame()
-		elapsed := time.Since(start)
-		sleepTime := (time.Second / 60) - elapsed
-		if sleepTime > 0 {
-			time.Sleep(sleepTime)
+		if addr, ok := system.CpuHalted(); ok && !halted {
+			halted = true
+			message := fmt.Sprintf("CPU halted by a JAM opcode at $%04X", addr)
+			fmt.Println(message)
+			window.SetTitle(windowTitle + " - " + message)
+		}
+
+		// save every few seconds too so a crash loses little progress
+		frames++
+		if cartridge != nil && frames%saveIntervalFrames == 0 {
+			err := cartridge.Save()
+			if err != nil {
+				fmt.Println(err.Error())
+			}
 		}
 
 		canvas.SetPixels(system.FrameBuffer())
@@ -55,6 +141,9 @@ func run() {
 		)
 		transMatrix = transMatrix.Moved(window.Bounds().Center())
 		canvas.Draw(window, transMatrix)
+		if overlay != nil {
+			overlay.draw(window, system)
+		}
 
 		window.Update()
 	}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+	"github.com/gopxl/pixel/v2/ext/text"
+	"github.com/theaaronruss/nes-emulator/internal/nes"
+	"golang.org/x/image/font/basicfont"
+)
+
+func isNsfFile(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".nsf" || ext == ".nsfe"
+}
+
+// nsfOverlay shows the song info on top of the otherwise blank screen and
+// steps through the tracks with the left and right arrow keys
+type nsfOverlay struct {
+	nsf  *nes.Nsf
+	txt  *text.Text
+	song int
+}
+
+func newNsfOverlay(nsf *nes.Nsf) *nsfOverlay {
+	atlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
+	return &nsfOverlay{
+		nsf:  nsf,
+		txt:  text.New(pixel.V(0, 0), atlas),
+		song: -1,
+	}
+}
+
+func (overlay *nsfOverlay) handleHotkeys(window *opengl.Window, system *nes.System) {
+	song := system.NsfSong()
+	if window.JustPressed(pixel.KeyRight) {
+		song = (song + 1) % system.NsfSongCount()
+		system.PlayNsfSong(song)
+	}
+	if window.JustPressed(pixel.KeyLeft) {
+		song = (song + system.NsfSongCount() - 1) % system.NsfSongCount()
+		system.PlayNsfSong(song)
+	}
+}
+
+func (overlay *nsfOverlay) draw(window *opengl.Window, system *nes.System) {
+	if song := system.NsfSong(); song != overlay.song {
+		overlay.song = song
+		overlay.txt.Clear()
+		fmt.Fprintln(overlay.txt, overlay.nsf.Title)
+		fmt.Fprintln(overlay.txt, overlay.nsf.Artist)
+		fmt.Fprintln(overlay.txt, overlay.nsf.Copyright)
+		fmt.Fprintln(overlay.txt)
+		fmt.Fprintf(overlay.txt, "Track %d/%d\n", song+1, system.NsfSongCount())
+		if song < len(overlay.nsf.TrackTitles) {
+			fmt.Fprintln(overlay.txt, overlay.nsf.TrackTitles[song])
+		}
+		fmt.Fprintln(overlay.txt)
+		fmt.Fprintln(overlay.txt, "Left/Right to change track")
+	}
+
+	bounds := window.Bounds()
+	pos := pixel.V(bounds.Min.X+16, bounds.Max.Y-16-overlay.txt.LineHeight*2)
+	overlay.txt.Draw(window, pixel.IM.Scaled(pixel.ZV, 2).Moved(pos))
+}
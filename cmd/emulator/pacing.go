@@ -0,0 +1,112 @@
+package main
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/theaaronruss/nes-emulator/internal/audio"
+	"github.com/theaaronruss/nes-emulator/internal/nes"
+)
+
+const (
+	// largest pitch change used to keep the audio buffer centered, small
+	// enough that it can't be heard
+	maxRateAdjust float64 = 0.005
+
+	// how long to sleep between checks of the audio buffer fill level
+	audioPollInterval time.Duration = time.Millisecond
+
+	// how much of the wait before a frame is spent spinning instead of
+	// sleeping, since sleeps can overshoot by about this much
+	spinDuration time.Duration = 2 * time.Millisecond
+
+	// how far behind the clock can fall before it gives up catching up
+	maxFrameLag int = 3
+
+	// how long to wait for the audio device to drain the buffer before taking
+	// it to have stalled, about two frames
+	maxAudioWait time.Duration = 2 * time.Second / 60
+)
+
+type framePacer interface {
+	waitForNextFrame()
+}
+
+// audioPacer waits for the audio device to drain the buffer down to half full
+// before each frame and nudges the resampling rate so that the buffer stays
+// centered instead of slowly running dry or overflowing. if the device stops
+// draining the buffer it falls back to the clock until it starts again, so
+// the window keeps responding
+type audioPacer struct {
+	system  *nes.System
+	buffer  *audio.RingBuffer
+	target  int
+	clock   *clockPacer
+	stalled bool
+}
+
+func newAudioPacer(system *nes.System, buffer *audio.RingBuffer) *audioPacer {
+	return &audioPacer{
+		system: system,
+		buffer: buffer,
+		target: buffer.Cap() / 2,
+		clock:  newClockPacer(),
+	}
+}
+
+func (pacer *audioPacer) waitForNextFrame() {
+	if pacer.stalled {
+		if pacer.buffer.Len() > pacer.target {
+			pacer.clock.waitForNextFrame()
+			return
+		}
+		pacer.stalled = false
+	}
+
+	deadline := time.Now().Add(maxAudioWait)
+	fill := pacer.buffer.Len()
+	for fill > pacer.target {
+		if time.Now().After(deadline) {
+			pacer.stalled = true
+			pacer.clock.next = time.Now()
+			pacer.system.SetAudioRateAdjust(1)
+			return
+		}
+		time.Sleep(audioPollInterval)
+		fill = pacer.buffer.Len()
+	}
+
+	offset := float64(pacer.target-fill) / float64(pacer.target)
+	pacer.system.SetAudioRateAdjust(1 + offset*maxRateAdjust)
+}
+
+// clockPacer schedules frames against the wall clock at the exact ntsc frame
+// rate for when there is no audio device to sync to
+type clockPacer struct {
+	period time.Duration
+	next   time.Time
+}
+
+func newClockPacer() *clockPacer {
+	framesPerSecond := nes.FrameRate
+	return &clockPacer{
+		period: time.Duration(float64(time.Second) / framesPerSecond),
+		next:   time.Now(),
+	}
+}
+
+func (pacer *clockPacer) waitForNextFrame() {
+	pacer.next = pacer.next.Add(pacer.period)
+	now := time.Now()
+	if now.Sub(pacer.next) > time.Duration(maxFrameLag)*pacer.period {
+		pacer.next = now
+		return
+	}
+
+	if sleepTime := time.Until(pacer.next) - spinDuration; sleepTime > 0 {
+		time.Sleep(sleepTime)
+	}
+	for time.Now().Before(pacer.next) {
+		runtime.Gosched()
+	}
+}
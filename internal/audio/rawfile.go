@@ -0,0 +1,66 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// RawFile writes samples to a headerless file as signed 16-bit little endian
+// mono pcm
+type RawFile struct {
+	sampleRate int
+	file       *os.File
+	writer     *bufio.Writer
+	err        error
+}
+
+func NewRawFile(filePath string, sampleRate int) (*RawFile, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio file: %w", err)
+	}
+	return &RawFile{
+		sampleRate: sampleRate,
+		file:       file,
+		writer:     bufio.NewWriter(file),
+	}, nil
+}
+
+func (raw *RawFile) SampleRate() int {
+	return raw.sampleRate
+}
+
+// WriteSamples stops writing after the first error, which is reported by
+// Close
+func (raw *RawFile) WriteSamples(samples []float32) {
+	if raw.err != nil {
+		return
+	}
+	for _, sample := range samples {
+		raw.err = binary.Write(raw.writer, binary.LittleEndian, toPcm16(sample))
+		if raw.err != nil {
+			return
+		}
+	}
+}
+
+func (raw *RawFile) Close() error {
+	flushErr := raw.writer.Flush()
+	closeErr := raw.file.Close()
+	switch {
+	case raw.err != nil:
+		return fmt.Errorf("failed to write audio file: %w", raw.err)
+	case flushErr != nil:
+		return fmt.Errorf("failed to write audio file: %w", flushErr)
+	case closeErr != nil:
+		return fmt.Errorf("failed to write audio file: %w", closeErr)
+	}
+	return nil
+}
+
+func toPcm16(sample float32) int16 {
+	sample = max(-1, min(1, sample))
+	return int16(sample * 32767)
+}
@@ -0,0 +1,36 @@
+package audio
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRawFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.raw")
+	raw, err := NewRawFile(path, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.WriteSamples([]float32{0, 0.5, -2, 1})
+	err = raw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := []int16{0, 16383, -32767, 32767}
+	if len(data) != len(samples)*2 {
+		t.Fatalf("file is %d bytes, want %d", len(data), len(samples)*2)
+	}
+	for i, want := range samples {
+		got := int16(binary.LittleEndian.Uint16(data[i*2:]))
+		if got != want {
+			t.Errorf("sample %d is %d, want %d", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package audio
+
+import "sync"
+
+type RingBuffer struct {
+	mu         sync.Mutex
+	sampleRate int
+	samples    []float32
+	readPos    int
+	length     int
+	lastSample float32
+}
+
+func NewRingBuffer(sampleRate int, capacity int) *RingBuffer {
+	return &RingBuffer{
+		sampleRate: sampleRate,
+		samples:    make([]float32, capacity),
+	}
+}
+
+func (buffer *RingBuffer) SampleRate() int {
+	return buffer.sampleRate
+}
+
+// WriteSamples drops any samples that don't fit so the emulator never blocks
+// on a slow audio device
+func (buffer *RingBuffer) WriteSamples(samples []float32) {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	for _, sample := range samples {
+		if buffer.length == len(buffer.samples) {
+			return
+		}
+		writePos := (buffer.readPos + buffer.length) % len(buffer.samples)
+		buffer.samples[writePos] = sample
+		buffer.length++
+	}
+}
+
+// Read fills out with buffered samples and returns how many were available.
+// on underrun the rest of out is padded with the last sample to avoid pops
+func (buffer *RingBuffer) Read(out []float32) int {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	n := min(len(out), buffer.length)
+	for i := range n {
+		out[i] = buffer.samples[buffer.readPos]
+		buffer.readPos = (buffer.readPos + 1) % len(buffer.samples)
+	}
+	buffer.length -= n
+
+	if n > 0 {
+		buffer.lastSample = out[n-1]
+	}
+	for i := n; i < len(out); i++ {
+		out[i] = buffer.lastSample
+	}
+	return n
+}
+
+func (buffer *RingBuffer) Len() int {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return buffer.length
+}
+
+func (buffer *RingBuffer) Cap() int {
+	return len(buffer.samples)
+}
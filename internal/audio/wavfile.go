@@ -0,0 +1,116 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	wavHeaderSize    = 44
+	wavBitsPerSample = 16
+	wavChannels      = 1
+)
+
+// WavFile writes samples to a 16-bit mono pcm wav file. the sizes in the
+// header are only correct once Close has been called
+type WavFile struct {
+	sampleRate int
+	file       *os.File
+	writer     *bufio.Writer
+	dataSize   uint32
+	err        error
+}
+
+func NewWavFile(filePath string, sampleRate int) (*WavFile, error) {
+	const errorMessage = "failed to create wav file: %w"
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, fmt.Errorf(errorMessage, err)
+	}
+
+	wav := &WavFile{
+		sampleRate: sampleRate,
+		file:       file,
+		writer:     bufio.NewWriter(file),
+	}
+	err = wav.writeHeader()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf(errorMessage, err)
+	}
+	return wav, nil
+}
+
+func (wav *WavFile) writeHeader() error {
+	const blockAlign = wavChannels * wavBitsPerSample / 8
+
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], wavHeaderSize-8+wav.dataSize)
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], wavChannels)
+	binary.LittleEndian.PutUint32(header[24:], uint32(wav.sampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(wav.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:], blockAlign)
+	binary.LittleEndian.PutUint16(header[34:], wavBitsPerSample)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], wav.dataSize)
+
+	_, err := wav.writer.Write(header)
+	return err
+}
+
+func (wav *WavFile) SampleRate() int {
+	return wav.sampleRate
+}
+
+// WriteSamples stops writing after the first error, which is reported by
+// Close
+func (wav *WavFile) WriteSamples(samples []float32) {
+	if wav.err != nil {
+		return
+	}
+	for _, sample := range samples {
+		wav.err = binary.Write(wav.writer, binary.LittleEndian, toPcm16(sample))
+		if wav.err != nil {
+			return
+		}
+		wav.dataSize += wavBitsPerSample / 8
+	}
+}
+
+// Close rewrites the header with the final sizes and closes the file
+func (wav *WavFile) Close() error {
+	const errorMessage = "failed to write wav file: %w"
+
+	err := wav.err
+	if err == nil {
+		err = wav.writer.Flush()
+	}
+	if err == nil {
+		_, err = wav.file.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		wav.writer.Reset(wav.file)
+		err = wav.writeHeader()
+	}
+	if err == nil {
+		err = wav.writer.Flush()
+	}
+
+	closeErr := wav.file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf(errorMessage, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWavFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	wav, err := NewWavFile(path, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wav.WriteSamples([]float32{0, 0.5})
+	wav.WriteSamples([]float32{-1, 1})
+	err = wav.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != wavHeaderSize+8 {
+		t.Fatalf("file is %d bytes, want %d", len(data), wavHeaderSize+8)
+	}
+
+	tests := []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{"riff size", binary.LittleEndian.Uint32(data[4:]), wavHeaderSize - 8 + 8},
+		{"format", uint32(binary.LittleEndian.Uint16(data[20:])), 1},
+		{"channels", uint32(binary.LittleEndian.Uint16(data[22:])), 1},
+		{"sample rate", binary.LittleEndian.Uint32(data[24:]), 48000},
+		{"byte rate", binary.LittleEndian.Uint32(data[28:]), 96000},
+		{"bits per sample", uint32(binary.LittleEndian.Uint16(data[34:])), 16},
+		{"data size", binary.LittleEndian.Uint32(data[40:]), 8},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s is %d, want %d", test.name, test.got, test.want)
+		}
+	}
+	for i, id := range map[int]string{0: "RIFF", 8: "WAVE", 12: "fmt ", 36: "data"} {
+		if string(data[i:i+4]) != id {
+			t.Errorf("expected %q at %d, got %q", id, i, data[i:i+4])
+		}
+	}
+
+	samples := []int16{0, 16383, -32767, 32767}
+	for i, want := range samples {
+		got := int16(binary.LittleEndian.Uint16(data[wavHeaderSize+i*2:]))
+		if got != want {
+			t.Errorf("sample %d is %d, want %d", i, got, want)
+		}
+	}
+}
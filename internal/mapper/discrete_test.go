@@ -0,0 +1,152 @@
+package mapper
+
+import "testing"
+
+// bankedRom returns rom where the first byte of each bank is the bank's
+// number and the rest is $FF, so writes to anywhere but the start of a bank
+// don't lose bits to bus conflicts
+func bankedRom(size int, bankSize int) []uint8 {
+	rom := make([]uint8, size)
+	for i := range rom {
+		rom[i] = 0xFF
+	}
+	for bank := range size / bankSize {
+		rom[bank*bankSize] = uint8(bank)
+	}
+	return rom
+}
+
+func readCpu(t *testing.T, mapper Mapper, addr uint16) uint8 {
+	t.Helper()
+	data, ok := mapper.ReadCpu(addr)
+	if !ok {
+		t.Fatalf("nothing mapped at $%04X", addr)
+	}
+	return data
+}
+
+func TestMapper002(t *testing.T) {
+	tests := []struct {
+		name      string
+		submapper int
+		writes    [][2]uint16
+		low       uint8
+		high      uint8
+	}{
+		{"power on", 0, nil, 0, 7},
+		{"switch", 0, [][2]uint16{{0x8001, 3}}, 3, 7},
+		{"bank number wraps", 0, [][2]uint16{{0xC001, 10}}, 2, 7},
+		{"bus conflict", 0, [][2]uint16{{0x8001, 2}, {0x8000, 5}}, 0, 7},
+		{"no bus conflicts", submapperNoBusConflicts, [][2]uint16{{0x8001, 2}, {0x8000, 5}}, 5, 7},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			board := &Board{
+				ProgramRom:    bankedRom(8*mapper002ProgramBankSize, mapper002ProgramBankSize),
+				CharacterData: make([]uint8, 8192),
+				Submapper:     test.submapper,
+			}
+			mapper := NewMapper002(board)
+			for _, write := range test.writes {
+				mapper.WriteCpu(write[0], uint8(write[1]))
+			}
+			if bank := readCpu(t, mapper, 0x8000); bank != test.low {
+				t.Errorf("bank %d at $8000, want %d", bank, test.low)
+			}
+			if bank := readCpu(t, mapper, 0xC000); bank != test.high {
+				t.Errorf("bank %d at $C000, want %d", bank, test.high)
+			}
+		})
+	}
+}
+
+func TestMapper003(t *testing.T) {
+	board := &Board{
+		ProgramRom:    bankedRom(32768, 32768),
+		CharacterData: bankedRom(4*mapper003CharacterBankSize, mapper003CharacterBankSize),
+	}
+	mapper := NewMapper003(board)
+	for _, bank := range []uint8{2, 3, 1, 0} {
+		mapper.WriteCpu(0x8001, bank)
+		if got := mapper.ReadPpu(0x0000); got != bank {
+			t.Errorf("chr bank %d, want %d", got, bank)
+		}
+	}
+}
+
+func TestMapper007(t *testing.T) {
+	tests := []struct {
+		data      uint8
+		bank      uint8
+		mirroring Mirroring
+	}{
+		{0x00, 0, MirrorSingleLower},
+		{0x05, 5, MirrorSingleLower},
+		{0x13, 3, MirrorSingleUpper},
+		{0xEF, 7, MirrorSingleLower},
+	}
+	for _, test := range tests {
+		board := &Board{
+			ProgramRom:    bankedRom(8*mapper007ProgramBankSize, mapper007ProgramBankSize),
+			CharacterData: make([]uint8, 8192),
+		}
+		mapper := NewMapper007(board)
+		mapper.WriteCpu(0x8001, test.data)
+		if bank := readCpu(t, mapper, 0x8000); bank != test.bank {
+			t.Errorf("write $%02X: bank %d, want %d", test.data, bank, test.bank)
+		}
+		if mirroring := mapper.Mirroring(); mirroring != test.mirroring {
+			t.Errorf("write $%02X: mirroring %d, want %d", test.data, mirroring, test.mirroring)
+		}
+	}
+}
+
+func TestMapper066(t *testing.T) {
+	board := &Board{
+		ProgramRom:    bankedRom(4*mapper066ProgramBankSize, mapper066ProgramBankSize),
+		CharacterData: bankedRom(4*mapper066CharacterBankSize, mapper066CharacterBankSize),
+	}
+	mapper := NewMapper066(board)
+	mapper.WriteCpu(0x8001, 0x21)
+	if bank := readCpu(t, mapper, 0x8000); bank != 2 {
+		t.Errorf("prg bank %d, want 2", bank)
+	}
+	if bank := mapper.ReadPpu(0x0000); bank != 1 {
+		t.Errorf("chr bank %d, want 1", bank)
+	}
+
+	// the write to $8000 conflicts with the bank number stored there
+	mapper.WriteCpu(0x8000, 0x33)
+	if bank := readCpu(t, mapper, 0x8000); bank != 0 {
+		t.Errorf("prg bank %d after a bus conflict, want 0", bank)
+	}
+	if bank := mapper.ReadPpu(0x0000); bank != 2 {
+		t.Errorf("chr bank %d after a bus conflict, want 2", bank)
+	}
+}
+
+func TestSmallCharacterRam(t *testing.T) {
+	// nes 2.0 headers can ask for less than 8k of chr ram, which is mirrored
+	tests := []struct {
+		name      string
+		newMapper func(*Board) Mapper
+	}{
+		{"000", NewMapper000},
+		{"002", NewMapper002},
+		{"007", NewMapper007},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			board := &Board{
+				ProgramRom:    make([]uint8, 32768),
+				CharacterData: make([]uint8, 2048),
+				CharacterRam:  true,
+			}
+			mapper := test.newMapper(board)
+			mapper.WritePpu(0x1801, 0x42)
+			if data := mapper.ReadPpu(0x0001); data != 0x42 {
+				t.Errorf("read $%02X, want $42", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package mapper
+
+const (
+	// the drive takes this many cpu cycles to read or write a byte, and this
+	// many to get back to the start of the disk
+	fdsByteCycles   int = 150
+	fdsRewindCycles int = 50000
+)
+
+// disk control bit masks
+const (
+	fdsMotorOn       uint8 = 0x01
+	fdsTransferReset uint8 = 0x02
+	fdsReadMode      uint8 = 0x04
+	fdsMirroring     uint8 = 0x08
+	fdsCrcControl    uint8 = 0x10
+	fdsTransferStart uint8 = 0x40
+	fdsTransferIrq   uint8 = 0x80
+)
+
+// io enable bit masks
+const (
+	fdsDiskRegisters  uint8 = 0x01
+	fdsSoundRegisters uint8 = 0x02
+)
+
+// irq control bit masks
+const (
+	fdsIrqRepeat  uint8 = 0x01
+	fdsIrqEnabled uint8 = 0x02
+)
+
+// Fds is the famicom disk system's ram adapter. the bios is its prg rom, it
+// has 32k of prg ram at $6000-$DFFF and 8k of chr ram. the disk drive is
+// emulated a byte at a time on a copy of each side that has the gaps the real
+// disk has
+type Fds struct {
+	board *Board
+	audio fdsAudio
+
+	sides [][]uint8
+	side  int
+	dirty bool
+
+	enabled    uint8
+	control    uint8
+	irqReload  uint16
+	irqCounter uint16
+	irqControl uint8
+	timerIrq   bool
+
+	motorOn          bool
+	endOfHead        bool
+	scanning         bool
+	gapEnded         bool
+	transferComplete bool
+	diskIrq          bool
+	position         int
+	delay            int
+	readData         uint8
+	writeData        uint8
+}
+
+// NewFds creates the ram adapter with the sides of a .fds image, starting
+// with the first side inserted
+func NewFds(board *Board, sides [][]uint8) *Fds {
+	mapper := &Fds{
+		board: board,
+		audio: newFdsAudio(),
+	}
+	for _, side := range sides {
+		mapper.sides = append(mapper.sides, addFdsGaps(side))
+	}
+	return mapper
+}
+
+func (mapper *Fds) DiskSideCount() int {
+	return len(mapper.sides)
+}
+
+// DiskSide returns the inserted side, or -1 when the disk is ejected
+func (mapper *Fds) DiskSide() int {
+	return mapper.side
+}
+
+// InsertDisk inserts a side of the disk. games wait for the disk to be
+// ejected before asking for another side
+func (mapper *Fds) InsertDisk(side int) {
+	if side >= 0 && side < len(mapper.sides) {
+		mapper.side = side
+	}
+}
+
+func (mapper *Fds) EjectDisk() {
+	mapper.side = -1
+}
+
+// Dirty reports whether a side has been written to since the last call to
+// DiskSides
+func (mapper *Fds) Dirty() bool {
+	return mapper.dirty
+}
+
+// DiskSides returns each side in the .fds format
+func (mapper *Fds) DiskSides() [][]uint8 {
+	mapper.dirty = false
+	sides := make([][]uint8, len(mapper.sides))
+	for i, side := range mapper.sides {
+		sides[i] = removeFdsGaps(side)
+	}
+	return sides
+}
+
+func (mapper *Fds) diskInserted() bool {
+	return mapper.side >= 0 && mapper.side < len(mapper.sides)
+}
+
+func (mapper *Fds) ClockCpu() {
+	mapper.clockTimer()
+	mapper.clockDrive()
+	mapper.audio.clock()
+}
+
+func (mapper *Fds) clockTimer() {
+	if mapper.irqControl&fdsIrqEnabled == 0 {
+		return
+	}
+	if mapper.irqCounter > 0 {
+		mapper.irqCounter--
+		return
+	}
+	mapper.timerIrq = true
+	mapper.irqCounter = mapper.irqReload
+	if mapper.irqControl&fdsIrqRepeat == 0 {
+		mapper.irqControl &^= fdsIrqEnabled
+	}
+}
+
+// clockDrive moves the disk under the head. it goes back to the start when it
+// reaches the end or the motor is stopped, and then reads or writes a byte
+// every 150 cycles
+func (mapper *Fds) clockDrive() {
+	if !mapper.diskInserted() || !mapper.motorOn {
+		mapper.endOfHead = true
+		mapper.scanning = false
+		return
+	}
+	if mapper.control&fdsTransferReset > 0 && !mapper.scanning {
+		return
+	}
+	if mapper.endOfHead {
+		mapper.delay = fdsRewindCycles
+		mapper.endOfHead = false
+		mapper.position = 0
+		mapper.gapEnded = false
+		return
+	}
+	if mapper.delay > 0 {
+		mapper.delay--
+		return
+	}
+
+	mapper.scanning = true
+	disk := mapper.sides[mapper.side]
+	transferIrq := mapper.control&fdsTransferIrq > 0
+	ready := mapper.control&fdsTransferStart > 0
+	if mapper.control&fdsReadMode > 0 {
+		data := disk[mapper.position]
+		if !ready {
+			mapper.gapEnded = false
+		} else if data != 0 && !mapper.gapEnded {
+			// the start bit after a gap isn't passed on to the bios
+			mapper.gapEnded = true
+			transferIrq = false
+		}
+		if mapper.gapEnded {
+			mapper.transferComplete = true
+			mapper.readData = data
+			mapper.diskIrq = mapper.diskIrq || transferIrq
+		}
+	} else {
+		var data uint8
+		if mapper.control&fdsCrcControl == 0 {
+			mapper.transferComplete = true
+			data = mapper.writeData
+			mapper.diskIrq = mapper.diskIrq || transferIrq
+		}
+		if !ready {
+			data = 0
+		}
+		disk[mapper.position] = data
+		mapper.dirty = true
+		mapper.gapEnded = false
+	}
+
+	mapper.position++
+	if mapper.position >= len(disk) {
+		mapper.motorOn = false
+	} else {
+		mapper.delay = fdsByteCycles
+	}
+}
+
+func (mapper *Fds) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0xE000:
+		programRom := mapper.board.ProgramRom
+		return programRom[int(addr-0xE000)%len(programRom)], true
+	case addr >= 0x6000:
+		programRam := mapper.board.ProgramRam
+		return programRam[int(addr-0x6000)%len(programRam)], true
+	case addr >= 0x4040 && mapper.enabled&fdsSoundRegisters > 0:
+		return mapper.audio.read(addr)
+	case addr >= 0x4030 && mapper.enabled&fdsDiskRegisters > 0:
+		return mapper.readDiskRegister(addr)
+	}
+	return 0, false
+}
+
+func (mapper *Fds) readDiskRegister(addr uint16) (uint8, bool) {
+	switch addr {
+	case 0x4030:
+		var status uint8
+		if mapper.timerIrq {
+			status |= 0x01
+		}
+		if mapper.transferComplete {
+			status |= 0x02
+		}
+		if mapper.endOfHead {
+			status |= 0x40
+		}
+		mapper.timerIrq = false
+		mapper.transferComplete = false
+		mapper.diskIrq = false
+		return status, true
+	case 0x4031:
+		mapper.transferComplete = false
+		mapper.diskIrq = false
+		return mapper.readData, true
+	case 0x4032:
+		var status uint8
+		if !mapper.diskInserted() {
+			status |= 0x05
+		}
+		if !mapper.diskInserted() || !mapper.scanning {
+			status |= 0x02
+		}
+		return status, true
+	case 0x4033:
+		// the battery is good
+		return 0x80, true
+	}
+	return 0, false
+}
+
+func (mapper *Fds) WriteCpu(addr uint16, data uint8) {
+	switch {
+	case addr >= 0xE000:
+	case addr >= 0x6000:
+		programRam := mapper.board.ProgramRam
+		programRam[int(addr-0x6000)%len(programRam)] = data
+	case addr >= 0x4040:
+		if mapper.enabled&fdsSoundRegisters > 0 {
+			mapper.audio.write(addr, data)
+		}
+	case addr == 0x4023:
+		mapper.enabled = data
+		if mapper.enabled&fdsDiskRegisters == 0 {
+			mapper.irqControl &^= fdsIrqEnabled
+			mapper.timerIrq = false
+			mapper.diskIrq = false
+		}
+	case addr < 0x4027 && mapper.enabled&fdsDiskRegisters > 0:
+		mapper.writeDiskRegister(addr, data)
+	}
+}
+
+func (mapper *Fds) writeDiskRegister(addr uint16, data uint8) {
+	switch addr {
+	case 0x4020:
+		mapper.irqReload = mapper.irqReload&0xFF00 | uint16(data)
+	case 0x4021:
+		mapper.irqReload = mapper.irqReload&0x00FF | uint16(data)<<8
+	case 0x4022:
+		mapper.irqControl = data
+		if data&fdsIrqEnabled > 0 {
+			mapper.irqCounter = mapper.irqReload
+		} else {
+			mapper.timerIrq = false
+		}
+	case 0x4024:
+		mapper.writeData = data
+		mapper.transferComplete = false
+		mapper.diskIrq = false
+	case 0x4025:
+		mapper.control = data
+		mapper.motorOn = data&fdsMotorOn > 0
+		mapper.diskIrq = false
+	}
+}
+
+func (mapper *Fds) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[int(addr)%len(mapper.board.CharacterData)]
+}
+
+func (mapper *Fds) WritePpu(addr uint16, data uint8) {
+	mapper.board.CharacterData[int(addr)%len(mapper.board.CharacterData)] = data
+}
+
+func (mapper *Fds) Mirroring() Mirroring {
+	if mapper.control&fdsMirroring > 0 {
+		return MirrorHorizontal
+	}
+	return MirrorVertical
+}
+
+func (mapper *Fds) Irq() bool {
+	return mapper.timerIrq || mapper.diskIrq
+}
+
+func (mapper *Fds) AudioSample() float32 {
+	return mapper.audio.sample()
+}
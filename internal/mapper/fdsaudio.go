@@ -0,0 +1,206 @@
+package mapper
+
+// the loudest fds output is about 2.4 times the loudest apu pulse
+const fdsLevel float32 = 0.36 / (63 * 32)
+
+// envelope bit masks
+const (
+	fdsEnvelopeSpeed    uint8 = 0x3F
+	fdsEnvelopeIncrease uint8 = 0x40
+	fdsEnvelopeOff      uint8 = 0x80
+)
+
+// frequency high bit masks
+const (
+	fdsEnvelopesHalt uint8 = 0x40
+	fdsWaveHalt      uint8 = 0x80
+)
+
+// wave write and master volume bit masks
+const (
+	fdsMasterVolume uint8 = 0x03
+	fdsWaveWrite    uint8 = 0x80
+)
+
+var fdsMasterVolumes = [4]float32{1, 2.0 / 3, 2.0 / 4, 2.0 / 5}
+
+// how each entry of the modulation table changes the mod counter, 4 resets it
+var fdsModulationSteps = [8]int{0, 1, 2, 4, 0, -4, -2, -1}
+
+// fdsEnvelope moves a gain up or down by one every time its period passes.
+// the gain can be set above 32 but the envelope stops there
+type fdsEnvelope struct {
+	control uint8
+	gain    uint8
+	counter int
+}
+
+func (envelope *fdsEnvelope) write(data uint8) {
+	envelope.control = data
+	envelope.counter = 0
+	if data&fdsEnvelopeOff > 0 {
+		envelope.gain = data & fdsEnvelopeSpeed
+	}
+}
+
+func (envelope *fdsEnvelope) clock(masterSpeed uint8) {
+	if envelope.control&fdsEnvelopeOff > 0 || masterSpeed == 0 {
+		return
+	}
+	envelope.counter++
+	if envelope.counter < 8*int(masterSpeed)*(int(envelope.control&fdsEnvelopeSpeed)+1) {
+		return
+	}
+	envelope.counter = 0
+	if envelope.control&fdsEnvelopeIncrease > 0 {
+		if envelope.gain < 32 {
+			envelope.gain++
+		}
+	} else if envelope.gain > 0 {
+		envelope.gain--
+	}
+}
+
+// fdsAudio is the disk system's sound, one channel that plays a 64 step
+// wavetable with its pitch bent by a second modulation table
+type fdsAudio struct {
+	wave         [64]uint8
+	control      uint8
+	frequency    uint16
+	frequencyHi  uint8
+	accumulator  uint32
+	volume       fdsEnvelope
+	masterSpeed  uint8
+	output       float32
+	modEnvelope  fdsEnvelope
+	modFrequency uint16
+	modHalt      bool
+	modTable     [64]uint8
+	modPosition  int
+	modAccum     uint32
+	modCounter   int
+}
+
+func newFdsAudio() fdsAudio {
+	return fdsAudio{masterSpeed: 0xE8, modHalt: true}
+}
+
+func (audio *fdsAudio) read(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0x4040 && addr < 0x4080:
+		return audio.wave[addr-0x4040] | 0x40, true
+	case addr == 0x4090:
+		return audio.volume.gain | 0x40, true
+	case addr == 0x4092:
+		return audio.modEnvelope.gain | 0x40, true
+	}
+	return 0, false
+}
+
+func (audio *fdsAudio) write(addr uint16, data uint8) {
+	switch addr {
+	case 0x4080:
+		audio.volume.write(data)
+	case 0x4082:
+		audio.frequency = audio.frequency&0x0F00 | uint16(data)
+	case 0x4083:
+		audio.frequency = audio.frequency&0x00FF | uint16(data&0x0F)<<8
+		audio.frequencyHi = data
+		if data&fdsWaveHalt > 0 {
+			audio.accumulator = 0
+		}
+	case 0x4084:
+		audio.modEnvelope.write(data)
+	case 0x4085:
+		audio.modCounter = int(data&0x3F) - int(data&0x40)
+	case 0x4086:
+		audio.modFrequency = audio.modFrequency&0x0F00 | uint16(data)
+	case 0x4087:
+		audio.modFrequency = audio.modFrequency&0x00FF | uint16(data&0x0F)<<8
+		audio.modHalt = data&0x80 > 0
+		if audio.modHalt {
+			audio.modAccum = 0
+		}
+	case 0x4088:
+		// the table can only be written while the modulator is halted, each
+		// write fills two entries
+		if audio.modHalt {
+			audio.modTable[audio.modPosition] = data & 0x07
+			audio.modTable[(audio.modPosition+1)&0x3F] = data & 0x07
+			audio.modPosition = (audio.modPosition + 2) & 0x3F
+		}
+	case 0x4089:
+		audio.control = data
+	case 0x408A:
+		audio.masterSpeed = data
+	default:
+		if addr >= 0x4040 && addr < 0x4080 && audio.control&fdsWaveWrite > 0 {
+			audio.wave[addr-0x4040] = data & 0x3F
+		}
+	}
+}
+
+// modulatedFrequency bends the wave's frequency by the mod counter the same
+// way the hardware does, including its odd rounding
+func (audio *fdsAudio) modulatedFrequency() int {
+	pitch := int(audio.frequency)
+	temp := audio.modCounter * int(audio.modEnvelope.gain)
+	remainder := temp & 0x0F
+	temp >>= 4
+	if remainder > 0 && temp&0x80 == 0 {
+		if audio.modCounter < 0 {
+			temp--
+		} else {
+			temp += 2
+		}
+	}
+
+	if temp >= 192 {
+		temp -= 256
+	} else if temp < -64 {
+		temp += 256
+	}
+
+	temp *= pitch
+	remainder = temp & 0x3F
+	temp >>= 6
+	if remainder >= 32 {
+		temp++
+	}
+	return max(pitch+temp, 0)
+}
+
+func (audio *fdsAudio) clock() {
+	waveHalt := audio.frequencyHi&fdsWaveHalt > 0
+	if !waveHalt && audio.frequencyHi&fdsEnvelopesHalt == 0 {
+		audio.volume.clock(audio.masterSpeed)
+		audio.modEnvelope.clock(audio.masterSpeed)
+	}
+
+	if !audio.modHalt && audio.modFrequency > 0 {
+		audio.modAccum += uint32(audio.modFrequency)
+		if audio.modAccum >= 0x10000 {
+			audio.modAccum -= 0x10000
+			step := audio.modTable[audio.modPosition]
+			if step == 4 {
+				audio.modCounter = 0
+			} else {
+				audio.modCounter = (audio.modCounter+fdsModulationSteps[step]+64)&0x7F - 64
+			}
+			audio.modPosition = (audio.modPosition + 1) & 0x3F
+		}
+	}
+
+	// the output holds its last value while the wave ram is being written
+	if waveHalt || audio.control&fdsWaveWrite > 0 {
+		return
+	}
+	audio.accumulator += uint32(audio.modulatedFrequency())
+	sample := audio.wave[audio.accumulator>>16&0x3F]
+	gain := min(audio.volume.gain, 32)
+	audio.output = float32(sample) * float32(gain) * fdsMasterVolumes[audio.control&fdsMasterVolume]
+}
+
+func (audio *fdsAudio) sample() float32 {
+	return audio.output * fdsLevel
+}
@@ -0,0 +1,89 @@
+package mapper
+
+const (
+	// FdsSideSize is the size of one disk side in a .fds image, which has
+	// none of the gaps or crcs that are on the real disk
+	FdsSideSize int = 65500
+
+	// the real disk starts with 28300 bits of gap and has 976 bits of gap
+	// after each block
+	fdsLeadingGap int = 28300 / 8
+	fdsBlockGap   int = 976 / 8
+
+	// room on a side for its gaps and for files the game adds
+	fdsSideCapacity int = 80000
+
+	// each block starts with a set bit after its gap
+	fdsBlockStart uint8 = 0x80
+)
+
+// fdsBlockLength returns the length of a block from its type, the file
+// data block's length comes from the file header block before it
+func fdsBlockLength(block []uint8, fileSize int) int {
+	switch block[0] {
+	case 1:
+		return 56
+	case 2:
+		return 2
+	case 3:
+		return 16
+	case 4:
+		return 1 + fileSize
+	}
+	return 0
+}
+
+// fdsFileSize reads the size of the next file from a file header block
+func fdsFileSize(block []uint8) int {
+	return int(block[13]) | int(block[14])<<8
+}
+
+// addFdsGaps turns a side from a .fds image into what the drive sees, with a
+// gap and a start bit before each block and a crc after it. the crcs are
+// never checked so they are left as zeros
+func addFdsGaps(side []uint8) []uint8 {
+	disk := make([]uint8, fdsLeadingGap, fdsSideCapacity)
+	fileSize := 0
+	for pos := 0; pos < len(side); {
+		length := fdsBlockLength(side[pos:], fileSize)
+		if length == 0 || pos+length > len(side) {
+			break
+		}
+		if side[pos] == 3 {
+			fileSize = fdsFileSize(side[pos:])
+		}
+		disk = append(disk, fdsBlockStart)
+		disk = append(disk, side[pos:pos+length]...)
+		disk = append(disk, 0, 0)
+		disk = append(disk, make([]uint8, fdsBlockGap)...)
+		pos += length
+	}
+	return disk[:fdsSideCapacity]
+}
+
+// removeFdsGaps does the opposite of addFdsGaps so a side can be saved in the
+// .fds format
+func removeFdsGaps(disk []uint8) []uint8 {
+	side := make([]uint8, 0, FdsSideSize)
+	fileSize := 0
+	pos := 0
+	for {
+		for pos < len(disk) && disk[pos] != fdsBlockStart {
+			pos++
+		}
+		pos++
+		if pos >= len(disk) {
+			break
+		}
+		length := fdsBlockLength(disk[pos:], fileSize)
+		if length == 0 || pos+length > len(disk) || len(side)+length > FdsSideSize {
+			break
+		}
+		if disk[pos] == 3 {
+			fileSize = fdsFileSize(disk[pos:])
+		}
+		side = append(side, disk[pos:pos+length]...)
+		pos += length + 2
+	}
+	return side[:FdsSideSize]
+}
@@ -0,0 +1,73 @@
+package mapper
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fdsSide builds a .fds side with the disk info and file count blocks
+// followed by a header and data block for each file
+func fdsSide(files ...[]uint8) []uint8 {
+	info := make([]uint8, 56)
+	info[0] = 1
+	copy(info[1:], "*NINTENDO-HVC*")
+	side := append(info, 2, uint8(len(files)))
+
+	for i, file := range files {
+		header := make([]uint8, 16)
+		header[0] = 3
+		header[1] = uint8(i)
+		header[13] = uint8(len(file))
+		header[14] = uint8(len(file) >> 8)
+		side = append(side, header...)
+		side = append(side, 4)
+		side = append(side, file...)
+	}
+	return append(side, make([]uint8, FdsSideSize-len(side))...)
+}
+
+func TestFdsGapsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		side []uint8
+	}{
+		{"blank", make([]uint8, FdsSideSize)},
+		{"no files", fdsSide()},
+		{"one file", fdsSide([]uint8{1, 2, 3, 4})},
+		{"start bits in the data", fdsSide(bytes.Repeat([]uint8{fdsBlockStart}, 300))},
+		{"several files", fdsSide([]uint8{9}, make([]uint8, 0x1234), []uint8{0x80, 0, 0x80})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			disk := addFdsGaps(test.side)
+			if len(disk) != fdsSideCapacity {
+				t.Errorf("disk is %d bytes, want %d", len(disk), fdsSideCapacity)
+			}
+			side := removeFdsGaps(disk)
+			if !bytes.Equal(side, test.side) {
+				t.Error("side changed going through the drive format and back")
+			}
+		})
+	}
+}
+
+func TestAddFdsGaps(t *testing.T) {
+	side := fdsSide()
+	disk := addFdsGaps(side)
+	if !bytes.Equal(disk[:fdsLeadingGap], make([]uint8, fdsLeadingGap)) {
+		t.Error("leading gap isn't blank")
+	}
+
+	// start bit, block, two crc bytes and a gap before the next start bit
+	pos := fdsLeadingGap
+	if disk[pos] != fdsBlockStart {
+		t.Fatalf("$%02X before the first block, want a start bit", disk[pos])
+	}
+	if !bytes.Equal(disk[pos+1:pos+57], side[:56]) {
+		t.Error("disk info block doesn't match")
+	}
+	pos += 1 + 56 + 2 + fdsBlockGap
+	if disk[pos] != fdsBlockStart || disk[pos+1] != 2 {
+		t.Errorf("$%02X $%02X at the second block, want a start bit and $02", disk[pos], disk[pos+1])
+	}
+}
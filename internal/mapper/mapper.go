@@ -1,10 +1,115 @@
 package mapper
 
+type Mirroring int
+
+const (
+	MirrorHorizontal Mirroring = iota
+	MirrorVertical
+	MirrorSingleLower
+	MirrorSingleUpper
+
+	// MirrorFourScreen is for boards with another 2k of vram so that each
+	// name table has its own memory
+	MirrorFourScreen
+
+	// MirrorMapperControlled is for mappers that decide what backs each name
+	// table themselves, see NameTableMapper
+	MirrorMapperControlled
+)
+
+// Board holds the memory on a cartridge that its mapper controls access to
+type Board struct {
+	ProgramRom    []uint8
+	ProgramRam    []uint8
+	CharacterData []uint8
+	CharacterRam  bool
+	Mirroring     Mirroring
+
+	// Submapper picks between boards that share a mapper number. it is 0
+	// when the rom doesn't say
+	Submapper int
+
+	// ProgramRamSized is set when the size of ProgramRam comes from a nes 2.0
+	// header, rather than being the usual size guessed for an ines rom
+	ProgramRamSized bool
+}
+
+// nes 2.0 submappers of the discrete logic boards that say whether writes
+// to the bank registers conflict with the rom
+const (
+	submapperNoBusConflicts int = 1
+	submapperBusConflicts   int = 2
+)
+
+// Mapper handles the cpu's accesses to $4020-$FFFF and the ppu's accesses to
+// $0000-$1FFF. ReadCpu returns false when nothing on the cartridge responds
+// to the address
 type Mapper interface {
-	TranslateProgramDataAddress(int, uint16) uint16
-	TranslateCharacterDataAddress(int, uint16) uint16
+	ReadCpu(addr uint16) (uint8, bool)
+	WriteCpu(addr uint16, data uint8)
+	ReadPpu(addr uint16) uint8
+	WritePpu(addr uint16, data uint8)
+	Mirroring() Mirroring
+	Irq() bool
+}
+
+// CpuClocker is implemented by mappers that need to be clocked every cpu
+// cycle, such as for irq counters
+type CpuClocker interface {
+	ClockCpu()
+}
+
+// PpuBusWatcher is implemented by mappers that need to see every address the
+// ppu puts on its bus, not just the ones that end up reading the cartridge
+type PpuBusWatcher interface {
+	WatchPpuAddr(addr uint16)
+}
+
+// NameTableMapper is implemented by mappers that decide what backs each of
+// the four name tables themselves instead of picking a mirroring. vram is the
+// console's own 2k of name table memory
+type NameTableMapper interface {
+	ReadNameTable(addr uint16, vram []uint8) uint8
+	WriteNameTable(addr uint16, data uint8, vram []uint8)
+}
+
+// CharacterVramMapper is implemented by mappers that can also put pages of
+// the console's name table memory in the pattern tables
+type CharacterVramMapper interface {
+	ReadCharacterVram(addr uint16, vram []uint8) uint8
+	WriteCharacterVram(addr uint16, data uint8, vram []uint8)
+}
+
+// CpuWriteWatcher is implemented by mappers that snoop on cpu writes below
+// $4020, such as the ones to the ppu's registers
+type CpuWriteWatcher interface {
+	WatchCpuWrite(addr uint16, data uint8)
+}
+
+// AudioSource is implemented by mappers with their own sound hardware. the
+// sample is on the same scale as the apu's mixed output
+type AudioSource interface {
+	AudioSample() float32
 }
 
-var Mappers = map[int]func() Mapper{
-	0: NewMapper000,
+var Mappers = map[int]func(*Board) Mapper{
+	0:  NewMapper000,
+	1:  NewMapper001,
+	2:  NewMapper002,
+	3:  NewMapper003,
+	4:  NewMapper004,
+	5:  NewMapper005,
+	7:  NewMapper007,
+	9:  NewMapper009,
+	10: NewMapper010,
+	19: NewMapper019,
+	21: NewMapper021,
+	22: NewMapper022,
+	23: NewMapper023,
+	24: NewMapper024,
+	25: NewMapper025,
+	26: NewMapper026,
+	66: NewMapper066,
+	69: NewMapper069,
+	85: NewMapper085,
 }
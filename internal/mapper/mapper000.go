@@ -1,19 +1,46 @@
 package mapper
 
 type Mapper000 struct {
+	board *Board
 }
 
-func NewMapper000() Mapper {
-	return &Mapper000{}
+func NewMapper000(board *Board) Mapper {
+	return &Mapper000{board: board}
 }
 
-func (mapper *Mapper000) TranslateProgramDataAddress(chunks int, addr uint16) uint16 {
-	if chunks == 1 {
-		return addr % 16384
+func (mapper *Mapper000) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0x8000:
+		programRom := mapper.board.ProgramRom
+		return programRom[int(addr-0x8000)%len(programRom)], true
+	case addr >= 0x6000 && len(mapper.board.ProgramRam) > 0:
+		programRam := mapper.board.ProgramRam
+		return programRam[int(addr-0x6000)%len(programRam)], true
 	}
-	return addr % 32768
+	return 0, false
 }
 
-func (mapper *Mapper000) TranslateCharacterDataAddress(chunks int, addr uint16) uint16 {
-	return addr % 8192
+func (mapper *Mapper000) WriteCpu(addr uint16, data uint8) {
+	if addr >= 0x6000 && addr < 0x8000 && len(mapper.board.ProgramRam) > 0 {
+		programRam := mapper.board.ProgramRam
+		programRam[int(addr-0x6000)%len(programRam)] = data
+	}
+}
+
+func (mapper *Mapper000) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[int(addr)%len(mapper.board.CharacterData)]
+}
+
+func (mapper *Mapper000) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[int(addr)%len(mapper.board.CharacterData)] = data
+	}
+}
+
+func (mapper *Mapper000) Mirroring() Mirroring {
+	return mapper.board.Mirroring
+}
+
+func (mapper *Mapper000) Irq() bool {
+	return false
 }
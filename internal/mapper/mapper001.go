@@ -0,0 +1,219 @@
+package mapper
+
+const (
+	mapper001ProgramBankSize   int = 16384
+	mapper001CharacterBankSize int = 4096
+	mapper001ProgramRamSize    int = 8192
+	mapper001OuterBankSize     int = 262144
+)
+
+// control bit masks
+const (
+	mapper001Mirroring     uint8 = 0x03
+	mapper001ProgramMode   uint8 = 0x0C
+	mapper001CharacterMode uint8 = 0x10
+)
+
+// Mapper001 is the mmc1. its registers are loaded one bit at a time through a
+// serial shift register. boards with 8k of chr use the upper bits of the chr
+// bank registers to select 256k prg halves (SUROM, SXROM) and prg ram banks
+// (SOROM, SXROM)
+type Mapper001 struct {
+	board *Board
+
+	shiftRegister  uint8
+	shiftCount     int
+	control        uint8
+	characterBank0 uint8
+	characterBank1 uint8
+	programBank    uint8
+	characterHigh  bool
+	cycle          int
+	lastWriteCycle int
+}
+
+func NewMapper001(board *Board) Mapper {
+	return &Mapper001{
+		board:          board,
+		control:        mapper001ProgramMode,
+		lastWriteCycle: -2,
+	}
+}
+
+func (mapper *Mapper001) ClockCpu() {
+	mapper.cycle++
+}
+
+func (mapper *Mapper001) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0x8000:
+		programRom := mapper.board.ProgramRom
+		return programRom[mapper.programRomOffset(addr)%len(programRom)], true
+	case addr >= 0x6000 && mapper.programRamEnabled():
+		return mapper.board.ProgramRam[mapper.programRamOffset(addr)], true
+	}
+	return 0, false
+}
+
+func (mapper *Mapper001) WriteCpu(addr uint16, data uint8) {
+	switch {
+	case addr >= 0x8000:
+		mapper.writeShiftRegister(addr, data)
+	case addr >= 0x6000 && mapper.programRamEnabled():
+		mapper.board.ProgramRam[mapper.programRamOffset(addr)] = data
+	}
+}
+
+// writeShiftRegister feeds bit 0 of each write into the shift register and
+// copies it to the register selected by the address on the fifth write. the
+// mmc1 ignores writes on back to back cycles, which games rely on when they
+// use read-modify-write instructions to reset it
+func (mapper *Mapper001) writeShiftRegister(addr uint16, data uint8) {
+	consecutive := mapper.cycle-mapper.lastWriteCycle <= 1
+	mapper.lastWriteCycle = mapper.cycle
+	if consecutive {
+		return
+	}
+
+	if data&0x80 > 0 {
+		mapper.shiftRegister = 0
+		mapper.shiftCount = 0
+		mapper.control |= mapper001ProgramMode
+		return
+	}
+
+	mapper.shiftRegister |= (data & 0x01) << mapper.shiftCount
+	mapper.shiftCount++
+	if mapper.shiftCount < 5 {
+		return
+	}
+
+	switch addr & 0xE000 {
+	case 0x8000:
+		mapper.control = mapper.shiftRegister
+	case 0xA000:
+		mapper.characterBank0 = mapper.shiftRegister
+	case 0xC000:
+		mapper.characterBank1 = mapper.shiftRegister
+	case 0xE000:
+		mapper.programBank = mapper.shiftRegister
+	}
+	mapper.shiftRegister = 0
+	mapper.shiftCount = 0
+}
+
+func (mapper *Mapper001) programRomOffset(addr uint16) int {
+	bankCount := len(mapper.board.ProgramRom) / mapper001ProgramBankSize
+	bank := int(mapper.programBank & 0x0F)
+	lastBank := 0x0F
+	if bankCount < 16 {
+		lastBank = bankCount - 1
+	}
+
+	// 512k boards select which 256k half is used with the chr bank register
+	outerBank := 0
+	if len(mapper.board.ProgramRom) > mapper001OuterBankSize {
+		outerBank = int(mapper.characterBank0>>4&0x01) * mapper001OuterBankSize
+	}
+
+	offset := int(addr-0x8000) % mapper001ProgramBankSize
+	upper := addr >= 0xC000
+	switch (mapper.control & mapper001ProgramMode) >> 2 {
+	case 0, 1:
+		bank &^= 0x01
+		if upper {
+			bank++
+		}
+	case 2:
+		if !upper {
+			bank = 0
+		}
+	case 3:
+		if upper {
+			bank = lastBank
+		}
+	}
+	return outerBank + bank*mapper001ProgramBankSize + offset
+}
+
+// programRamEnabled checks bit 4 of the prg bank register, and on SNROM bit 4
+// of the chr bank register in use, which is wired to the ram's chip enable.
+// boards with more than 256k of prg use that bit for the outer bank instead
+func (mapper *Mapper001) programRamEnabled() bool {
+	if len(mapper.board.ProgramRam) == 0 || mapper.programBank&0x10 > 0 {
+		return false
+	}
+	if len(mapper.board.CharacterData) <= 2*mapper001CharacterBankSize &&
+		len(mapper.board.ProgramRom) <= mapper001OuterBankSize {
+		return mapper.activeCharacterBank()&0x10 == 0
+	}
+	return true
+}
+
+// activeCharacterBank is the chr bank register the mmc1 is outputting, which
+// in 4k mode follows the ppu's A12
+func (mapper *Mapper001) activeCharacterBank() uint8 {
+	if mapper.control&mapper001CharacterMode > 0 && mapper.characterHigh {
+		return mapper.characterBank1
+	}
+	return mapper.characterBank0
+}
+
+// programRamOffset picks the 8k prg ram bank. SOROM uses bit 3 of the chr bank
+// register for its 16k and SXROM uses bits 2-3 for its 32k
+func (mapper *Mapper001) programRamOffset(addr uint16) int {
+	programRam := mapper.board.ProgramRam
+	bank := 0
+	switch {
+	case len(programRam) > 2*mapper001ProgramRamSize:
+		bank = int(mapper.characterBank0>>2) & 0x03
+	case len(programRam) > mapper001ProgramRamSize:
+		bank = int(mapper.characterBank0>>3) & 0x01
+	}
+	offset := bank*mapper001ProgramRamSize + int(addr-0x6000)%mapper001ProgramRamSize
+	return offset % len(programRam)
+}
+
+func (mapper *Mapper001) characterOffset(addr uint16) int {
+	var bank int
+	if mapper.control&mapper001CharacterMode == 0 {
+		bank = int(mapper.characterBank0 &^ 0x01)
+		if addr >= 0x1000 {
+			bank++
+		}
+	} else if addr < 0x1000 {
+		bank = int(mapper.characterBank0)
+	} else {
+		bank = int(mapper.characterBank1)
+	}
+	offset := bank*mapper001CharacterBankSize + int(addr)%mapper001CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+func (mapper *Mapper001) ReadPpu(addr uint16) uint8 {
+	mapper.characterHigh = addr&0x1000 > 0
+	return mapper.board.CharacterData[mapper.characterOffset(addr)]
+}
+
+func (mapper *Mapper001) WritePpu(addr uint16, data uint8) {
+	mapper.characterHigh = addr&0x1000 > 0
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(addr)] = data
+	}
+}
+
+func (mapper *Mapper001) Mirroring() Mirroring {
+	switch mapper.control & mapper001Mirroring {
+	case 0:
+		return MirrorSingleLower
+	case 1:
+		return MirrorSingleUpper
+	case 2:
+		return MirrorVertical
+	}
+	return MirrorHorizontal
+}
+
+func (mapper *Mapper001) Irq() bool {
+	return false
+}
@@ -0,0 +1,166 @@
+package mapper
+
+import "testing"
+
+// writeMapper001 loads a register through the shift register, a bit per
+// write with a cycle between writes so none of them are ignored
+func writeMapper001(mapper Mapper, addr uint16, value uint8) {
+	mmc1 := mapper.(*Mapper001)
+	for i := range 5 {
+		mmc1.ClockCpu()
+		mmc1.ClockCpu()
+		mmc1.WriteCpu(addr, value>>i&0x01)
+	}
+}
+
+func newTestMapper001(programSize int) Mapper {
+	return NewMapper001(&Board{
+		ProgramRom:    bankedRom(programSize, mapper001ProgramBankSize),
+		ProgramRam:    make([]uint8, mapper001ProgramRamSize),
+		CharacterData: bankedRom(32*mapper001CharacterBankSize, mapper001CharacterBankSize),
+	})
+}
+
+func TestMapper001ProgramBanks(t *testing.T) {
+	tests := []struct {
+		name    string
+		control uint8
+		bank    uint8
+		low     uint8
+		high    uint8
+	}{
+		{"32k mode", 0x00, 5, 4, 5},
+		{"fixed first bank", 0x08, 5, 0, 5},
+		{"fixed last bank", 0x0C, 5, 5, 15},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := newTestMapper001(16 * mapper001ProgramBankSize)
+			writeMapper001(mapper, 0x8000, test.control)
+			writeMapper001(mapper, 0xE000, test.bank)
+			if bank := readCpu(t, mapper, 0x8000); bank != test.low {
+				t.Errorf("bank %d at $8000, want %d", bank, test.low)
+			}
+			if bank := readCpu(t, mapper, 0xC000); bank != test.high {
+				t.Errorf("bank %d at $C000, want %d", bank, test.high)
+			}
+		})
+	}
+}
+
+func TestMapper001OuterBank(t *testing.T) {
+	// SUROM picks the 256k half with bit 4 of the first chr bank register
+	mapper := newTestMapper001(32 * mapper001ProgramBankSize)
+	writeMapper001(mapper, 0xE000, 2)
+	writeMapper001(mapper, 0xA000, 0x10)
+	if bank := readCpu(t, mapper, 0x8000); bank != 18 {
+		t.Errorf("bank %d at $8000, want 18", bank)
+	}
+	if bank := readCpu(t, mapper, 0xC000); bank != 31 {
+		t.Errorf("bank %d at $C000, want 31", bank)
+	}
+}
+
+func TestMapper001CharacterBanks(t *testing.T) {
+	tests := []struct {
+		name    string
+		control uint8
+		low     uint8
+		high    uint8
+	}{
+		{"8k mode", 0x0C, 6, 7},
+		{"4k mode", 0x1C, 7, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := newTestMapper001(2 * mapper001ProgramBankSize)
+			writeMapper001(mapper, 0x8000, test.control)
+			writeMapper001(mapper, 0xA000, 7)
+			writeMapper001(mapper, 0xC000, 3)
+			if bank := mapper.ReadPpu(0x0000); bank != test.low {
+				t.Errorf("bank %d at $0000, want %d", bank, test.low)
+			}
+			if bank := mapper.ReadPpu(0x1000); bank != test.high {
+				t.Errorf("bank %d at $1000, want %d", bank, test.high)
+			}
+		})
+	}
+}
+
+func TestMapper001ConsecutiveWrites(t *testing.T) {
+	// writes on back to back cycles are ignored after the first, so only one
+	// bit of each pair gets in and the register needs ten writes
+	mapper := newTestMapper001(16 * mapper001ProgramBankSize)
+	mmc1 := mapper.(*Mapper001)
+	for i := range 5 {
+		mmc1.ClockCpu()
+		mmc1.ClockCpu()
+		mmc1.WriteCpu(0xE000, 3>>i&0x01)
+		mmc1.ClockCpu()
+		mmc1.WriteCpu(0xE000, 0x01)
+	}
+	if bank := readCpu(t, mapper, 0x8000); bank != 3 {
+		t.Errorf("bank %d at $8000, want 3", bank)
+	}
+}
+
+func TestMapper001Reset(t *testing.T) {
+	mapper := newTestMapper001(16 * mapper001ProgramBankSize)
+	writeMapper001(mapper, 0x8000, 0x00)
+	mapper.(*Mapper001).ClockCpu()
+	mapper.(*Mapper001).ClockCpu()
+	mapper.WriteCpu(0x8000, 0x80)
+	writeMapper001(mapper, 0xE000, 5)
+	if bank := readCpu(t, mapper, 0xC000); bank != 15 {
+		t.Errorf("bank %d at $C000 after a reset, want 15", bank)
+	}
+}
+
+func TestMapper001ProgramRam(t *testing.T) {
+	mapper := newTestMapper001(2 * mapper001ProgramBankSize)
+	mapper.WriteCpu(0x6123, 0x42)
+	if data := readCpu(t, mapper, 0x6123); data != 0x42 {
+		t.Errorf("read $%02X from prg ram, want $42", data)
+	}
+
+	// bit 4 of the prg bank register disables prg ram
+	writeMapper001(mapper, 0xE000, 0x10)
+	if _, ok := mapper.ReadCpu(0x6123); ok {
+		t.Error("prg ram still readable when disabled")
+	}
+}
+
+func TestMapper001ProgramRamChipEnable(t *testing.T) {
+	// SNROM disables prg ram with bit 4 of the chr bank register that's in use
+	tests := []struct {
+		name    string
+		control uint8
+		bank0   uint8
+		bank1   uint8
+		ppuAddr uint16
+		enabled bool
+	}{
+		{"8k mode enabled", 0x0C, 0x00, 0x10, 0x1000, true},
+		{"8k mode disabled", 0x0C, 0x10, 0x00, 0x1000, false},
+		{"4k mode low bank", 0x1C, 0x10, 0x00, 0x0000, false},
+		{"4k mode high bank", 0x1C, 0x10, 0x00, 0x1000, true},
+		{"4k mode high bank disabled", 0x1C, 0x00, 0x10, 0x1000, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := NewMapper001(&Board{
+				ProgramRom:    bankedRom(16*mapper001ProgramBankSize, mapper001ProgramBankSize),
+				ProgramRam:    make([]uint8, mapper001ProgramRamSize),
+				CharacterData: make([]uint8, 8192),
+				CharacterRam:  true,
+			})
+			writeMapper001(mapper, 0x8000, test.control)
+			writeMapper001(mapper, 0xA000, test.bank0)
+			writeMapper001(mapper, 0xC000, test.bank1)
+			mapper.ReadPpu(test.ppuAddr)
+			if _, ok := mapper.ReadCpu(0x6000); ok != test.enabled {
+				t.Errorf("prg ram enabled is %v, want %v", ok, test.enabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package mapper
+
+const mapper002ProgramBankSize int = 16384
+
+// Mapper002 is UxROM. the bank at $8000 can be switched and the last bank is
+// fixed at $C000. these boards usually have chr ram
+type Mapper002 struct {
+	board *Board
+
+	programBank int
+}
+
+func NewMapper002(board *Board) Mapper {
+	return &Mapper002{board: board}
+}
+
+func (mapper *Mapper002) ReadCpu(addr uint16) (uint8, bool) {
+	if addr < 0x8000 {
+		return 0, false
+	}
+
+	bankCount := len(mapper.board.ProgramRom) / mapper002ProgramBankSize
+	bank := mapper.programBank
+	if addr >= 0xC000 {
+		bank = bankCount - 1
+	}
+	offset := bank*mapper002ProgramBankSize + int(addr)%mapper002ProgramBankSize
+	return mapper.board.ProgramRom[offset%len(mapper.board.ProgramRom)], true
+}
+
+func (mapper *Mapper002) WriteCpu(addr uint16, data uint8) {
+	if addr < 0x8000 {
+		return
+	}
+
+	// the rom drives the data bus at the same time, so only bits that are
+	// set in both survive
+	if mapper.board.Submapper != submapperNoBusConflicts {
+		value, _ := mapper.ReadCpu(addr)
+		data &= value
+	}
+	mapper.programBank = int(data)
+}
+
+func (mapper *Mapper002) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[int(addr)%len(mapper.board.CharacterData)]
+}
+
+func (mapper *Mapper002) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[int(addr)%len(mapper.board.CharacterData)] = data
+	}
+}
+
+func (mapper *Mapper002) Mirroring() Mirroring {
+	return mapper.board.Mirroring
+}
+
+func (mapper *Mapper002) Irq() bool {
+	return false
+}
@@ -0,0 +1,59 @@
+package mapper
+
+const mapper003CharacterBankSize int = 8192
+
+// Mapper003 is CNROM, which has fixed prg rom and switches the whole 8k of
+// chr rom at once
+type Mapper003 struct {
+	board *Board
+
+	characterBank int
+}
+
+func NewMapper003(board *Board) Mapper {
+	return &Mapper003{board: board}
+}
+
+func (mapper *Mapper003) ReadCpu(addr uint16) (uint8, bool) {
+	if addr < 0x8000 {
+		return 0, false
+	}
+	programRom := mapper.board.ProgramRom
+	return programRom[int(addr-0x8000)%len(programRom)], true
+}
+
+func (mapper *Mapper003) WriteCpu(addr uint16, data uint8) {
+	if addr < 0x8000 {
+		return
+	}
+
+	// bus conflict with the rom
+	if mapper.board.Submapper != submapperNoBusConflicts {
+		value, _ := mapper.ReadCpu(addr)
+		data &= value
+	}
+	mapper.characterBank = int(data)
+}
+
+func (mapper *Mapper003) characterOffset(addr uint16) int {
+	offset := mapper.characterBank*mapper003CharacterBankSize + int(addr)%mapper003CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+func (mapper *Mapper003) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[mapper.characterOffset(addr)]
+}
+
+func (mapper *Mapper003) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(addr)] = data
+	}
+}
+
+func (mapper *Mapper003) Mirroring() Mirroring {
+	return mapper.board.Mirroring
+}
+
+func (mapper *Mapper003) Irq() bool {
+	return false
+}
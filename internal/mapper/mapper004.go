@@ -0,0 +1,203 @@
+package mapper
+
+const (
+	mapper004ProgramBankSize   int = 8192
+	mapper004CharacterBankSize int = 1024
+
+	// a12 has to stay low for this many cpu cycles before a rising edge
+	// clocks the irq counter, which filters out the sprite fetches
+	mapper004A12LowCycles int = 3
+)
+
+// bank select bit masks
+const (
+	mapper004BankRegister      uint8 = 0x07
+	mapper004ProgramMode       uint8 = 0x40
+	mapper004CharacterInverted uint8 = 0x80
+)
+
+// prg ram protect bit masks
+const (
+	mapper004RamEnabled      uint8 = 0x80
+	mapper004RamWriteProtect uint8 = 0x40
+)
+
+// Mapper004 is the mmc3. its irq counter is clocked by rising edges on ppu
+// address line 12, which normally happen once per scan line when the
+// background uses the pattern table at $0000 and sprites the one at $1000
+type Mapper004 struct {
+	board *Board
+
+	bankSelect   uint8
+	banks        [8]uint8
+	mirroring    Mirroring
+	ramProtect   uint8
+	irqLatch     uint8
+	irqCounter   uint8
+	irqReload    bool
+	irqEnabled   bool
+	irqPending   bool
+	a12          bool
+	a12LowCycle  int
+	cycle        int
+	programBanks int
+}
+
+func NewMapper004(board *Board) Mapper {
+	return &Mapper004{
+		board:        board,
+		mirroring:    board.Mirroring,
+		ramProtect:   mapper004RamEnabled,
+		programBanks: len(board.ProgramRom) / mapper004ProgramBankSize,
+	}
+}
+
+func (mapper *Mapper004) ClockCpu() {
+	mapper.cycle++
+}
+
+func (mapper *Mapper004) WatchPpuAddr(addr uint16) {
+	a12 := addr&0x1000 > 0
+	if a12 && !mapper.a12 && mapper.cycle-mapper.a12LowCycle >= mapper004A12LowCycles {
+		mapper.clockIrqCounter()
+	}
+	if !a12 && mapper.a12 {
+		mapper.a12LowCycle = mapper.cycle
+	}
+	mapper.a12 = a12
+}
+
+func (mapper *Mapper004) clockIrqCounter() {
+	if mapper.irqCounter == 0 || mapper.irqReload {
+		mapper.irqCounter = mapper.irqLatch
+		mapper.irqReload = false
+	} else {
+		mapper.irqCounter--
+	}
+	if mapper.irqCounter == 0 && mapper.irqEnabled {
+		mapper.irqPending = true
+	}
+}
+
+func (mapper *Mapper004) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0x8000:
+		return mapper.board.ProgramRom[mapper.programRomOffset(addr)], true
+	case addr >= 0x6000 && mapper.programRamEnabled():
+		programRam := mapper.board.ProgramRam
+		return programRam[int(addr-0x6000)%len(programRam)], true
+	}
+	return 0, false
+}
+
+func (mapper *Mapper004) WriteCpu(addr uint16, data uint8) {
+	if addr >= 0x6000 && addr < 0x8000 {
+		if mapper.programRamEnabled() && mapper.ramProtect&mapper004RamWriteProtect == 0 {
+			programRam := mapper.board.ProgramRam
+			programRam[int(addr-0x6000)%len(programRam)] = data
+		}
+		return
+	}
+	if addr < 0x8000 {
+		return
+	}
+
+	even := addr&0x01 == 0
+	switch addr & 0xE000 {
+	case 0x8000:
+		if even {
+			mapper.bankSelect = data
+		} else {
+			mapper.banks[mapper.bankSelect&mapper004BankRegister] = data
+		}
+	case 0xA000:
+		if even {
+			if data&0x01 == 0 {
+				mapper.mirroring = MirrorVertical
+			} else {
+				mapper.mirroring = MirrorHorizontal
+			}
+		} else {
+			mapper.ramProtect = data
+		}
+	case 0xC000:
+		if even {
+			mapper.irqLatch = data
+		} else {
+			mapper.irqCounter = 0
+			mapper.irqReload = true
+		}
+	case 0xE000:
+		if even {
+			mapper.irqEnabled = false
+			mapper.irqPending = false
+		} else {
+			mapper.irqEnabled = true
+		}
+	}
+}
+
+func (mapper *Mapper004) programRamEnabled() bool {
+	return len(mapper.board.ProgramRam) > 0 && mapper.ramProtect&mapper004RamEnabled > 0
+}
+
+func (mapper *Mapper004) programRomOffset(addr uint16) int {
+	secondLast := mapper.programBanks - 2
+	var bank int
+	switch (addr - 0x8000) / 0x2000 {
+	case 0:
+		bank = int(mapper.banks[6])
+		if mapper.bankSelect&mapper004ProgramMode > 0 {
+			bank = secondLast
+		}
+	case 1:
+		bank = int(mapper.banks[7])
+	case 2:
+		bank = secondLast
+		if mapper.bankSelect&mapper004ProgramMode > 0 {
+			bank = int(mapper.banks[6])
+		}
+	case 3:
+		bank = mapper.programBanks - 1
+	}
+	bank %= mapper.programBanks
+	return bank*mapper004ProgramBankSize + int(addr)%mapper004ProgramBankSize
+}
+
+// characterOffset maps the two 2k and four 1k chr banks, which swap halves of
+// the pattern table space when chr inversion is on
+func (mapper *Mapper004) characterOffset(addr uint16) int {
+	if mapper.bankSelect&mapper004CharacterInverted > 0 {
+		addr ^= 0x1000
+	}
+
+	var bank int
+	switch {
+	case addr < 0x0800:
+		bank = int(mapper.banks[0]&0xFE) + int(addr/0x0400)
+	case addr < 0x1000:
+		bank = int(mapper.banks[1]&0xFE) + int(addr-0x0800)/0x0400
+	default:
+		bank = int(mapper.banks[2+(addr-0x1000)/0x0400])
+	}
+	offset := bank*mapper004CharacterBankSize + int(addr)%mapper004CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+func (mapper *Mapper004) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[mapper.characterOffset(addr)]
+}
+
+func (mapper *Mapper004) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(addr)] = data
+	}
+}
+
+func (mapper *Mapper004) Mirroring() Mirroring {
+	return mapper.mirroring
+}
+
+func (mapper *Mapper004) Irq() bool {
+	return mapper.irqPending
+}
@@ -0,0 +1,159 @@
+package mapper
+
+import "testing"
+
+func newTestMapper004() Mapper {
+	return NewMapper004(&Board{
+		ProgramRom:    bankedRom(16*mapper004ProgramBankSize, mapper004ProgramBankSize),
+		CharacterData: bankedRom(256*mapper004CharacterBankSize, mapper004CharacterBankSize),
+	})
+}
+
+// scanLine drives a12 the way the ppu does for a scan line with the
+// background at $0000 and sprites at $1000, a single rising edge after it has
+// been low for a while
+func scanLine(mapper Mapper) {
+	mmc3 := mapper.(*Mapper004)
+	mmc3.WatchPpuAddr(0x0000)
+	for range 100 {
+		mmc3.ClockCpu()
+	}
+	mmc3.WatchPpuAddr(0x1000)
+	mmc3.ClockCpu()
+}
+
+func TestMapper004ProgramBanks(t *testing.T) {
+	tests := []struct {
+		name       string
+		bankSelect uint8
+		banks      [4]uint8
+	}{
+		{"$8000 swappable", 0x00, [4]uint8{3, 5, 14, 15}},
+		{"$C000 swappable", 0x40, [4]uint8{14, 5, 3, 15}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := newTestMapper004()
+			mapper.WriteCpu(0x8000, 6)
+			mapper.WriteCpu(0x8001, 3)
+			mapper.WriteCpu(0x8000, 7)
+			mapper.WriteCpu(0x8001, 5)
+			mapper.WriteCpu(0x8000, test.bankSelect)
+			for i, want := range test.banks {
+				addr := 0x8000 + uint16(i)*0x2000
+				if bank := readCpu(t, mapper, addr); bank != want {
+					t.Errorf("bank %d at $%04X, want %d", bank, addr, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMapper004CharacterBanks(t *testing.T) {
+	tests := []struct {
+		name       string
+		bankSelect uint8
+		banks      [8]uint8
+	}{
+		{"normal", 0x00, [8]uint8{16, 17, 32, 33, 40, 41, 42, 43}},
+		{"inverted", 0x80, [8]uint8{40, 41, 42, 43, 16, 17, 32, 33}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := newTestMapper004()
+			// the 2k banks ignore the low bit
+			for register, bank := range []uint8{17, 33, 40, 41, 42, 43} {
+				mapper.WriteCpu(0x8000, uint8(register))
+				mapper.WriteCpu(0x8001, bank)
+			}
+			mapper.WriteCpu(0x8000, test.bankSelect)
+			for i, want := range test.banks {
+				addr := uint16(i) * 0x0400
+				if bank := mapper.ReadPpu(addr); bank != want {
+					t.Errorf("bank %d at $%04X, want %d", bank, addr, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMapper004Irq(t *testing.T) {
+	tests := []struct {
+		name    string
+		latch   uint8
+		enabled bool
+		want    []bool
+	}{
+		// the first edge loads the counter from the latch, the irq fires on
+		// the edge that takes it to zero
+		{"latch 3", 3, true, []bool{false, false, false, true}},
+		{"latch 1", 1, true, []bool{false, true}},
+		{"latch 0 fires every line", 0, true, []bool{true, true}},
+		{"disabled", 1, false, []bool{false, false, false}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := newTestMapper004()
+			mapper.WriteCpu(0xC000, test.latch)
+			mapper.WriteCpu(0xC001, 0)
+			if test.enabled {
+				mapper.WriteCpu(0xE001, 0)
+			}
+			for line, want := range test.want {
+				scanLine(mapper)
+				if irq := mapper.Irq(); irq != want {
+					t.Errorf("irq %v after line %d, want %v", irq, line+1, want)
+				}
+				// acknowledge it and enable it again
+				mapper.WriteCpu(0xE000, 0)
+				if test.enabled {
+					mapper.WriteCpu(0xE001, 0)
+				}
+			}
+		})
+	}
+}
+
+func TestMapper004A12Filter(t *testing.T) {
+	mapper := newTestMapper004()
+	mmc3 := mapper.(*Mapper004)
+	mapper.WriteCpu(0xC000, 1)
+	mapper.WriteCpu(0xC001, 0)
+	mapper.WriteCpu(0xE001, 0)
+	scanLine(mapper)
+
+	// edges closer together than the filter allows, like the ones from 8x16
+	// sprite fetches, don't clock the counter
+	for range 8 {
+		mmc3.WatchPpuAddr(0x0000)
+		mmc3.ClockCpu()
+		mmc3.WatchPpuAddr(0x1000)
+	}
+	if mapper.Irq() {
+		t.Error("irq from filtered a12 edges")
+	}
+	scanLine(mapper)
+	if !mapper.Irq() {
+		t.Error("no irq after the next scan line")
+	}
+}
+
+func TestMapper004ReloadMidCount(t *testing.T) {
+	// writing $C001 clears the counter so the next edge reloads the latch
+	mapper := newTestMapper004()
+	mapper.WriteCpu(0xC000, 3)
+	mapper.WriteCpu(0xC001, 0)
+	mapper.WriteCpu(0xE001, 0)
+	scanLine(mapper)
+	scanLine(mapper)
+	mapper.WriteCpu(0xC000, 1)
+	mapper.WriteCpu(0xC001, 0)
+	scanLine(mapper)
+	if mapper.Irq() {
+		t.Error("irq on the edge that reloaded the counter")
+	}
+	scanLine(mapper)
+	if !mapper.Irq() {
+		t.Error("no irq after counting down the new latch")
+	}
+}
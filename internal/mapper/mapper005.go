@@ -0,0 +1,464 @@
+package mapper
+
+const (
+	mapper005ProgramBankSize int = 8192
+	mapper005ProgramRamSize  int = 65536
+	mapper005ExRamSize       int = 1024
+	mapper005SplitBankSize   int = 4096
+
+	// the ppu stops reading during vblank and when rendering is off. the
+	// mmc5 takes this many cpu cycles without a read to mean the frame ended
+	mapper005IdleCycles int = 3
+
+	// name table reads in a scan line, counted from the one at dot 1, that
+	// belong to the sprite fetches
+	mapper005SpriteFetchStart int = 33
+	mapper005SpriteFetchEnd   int = 48
+)
+
+// exram modes
+const (
+	mapper005ExRamNameTable uint8 = iota
+	mapper005ExRamAttributes
+	mapper005ExRamWritable
+	mapper005ExRamReadOnly
+)
+
+// name table sources
+const (
+	mapper005NameTableVram0 uint8 = iota
+	mapper005NameTableVram1
+	mapper005NameTableExRam
+	mapper005NameTableFill
+)
+
+// split control bit masks
+const (
+	mapper005SplitEnabled   uint8 = 0x80
+	mapper005SplitRightSide uint8 = 0x40
+	mapper005SplitTiles     uint8 = 0x1F
+)
+
+// Mapper005 is the mmc5. besides its bank switching it follows what the ppu
+// is fetching to detect scan lines and to swap in data of its own: extended
+// attributes and a vertical split screen from exram, separate chr banks for
+// the background and 8x16 sprites, and fill mode name tables
+type Mapper005 struct {
+	board *Board
+	audio mapper005Audio
+
+	programMode    uint8
+	characterMode  uint8
+	ramProtect1    uint8
+	ramProtect2    uint8
+	exRamMode      uint8
+	nameTables     uint8
+	fillTile       uint8
+	fillAttr       uint8
+	programRamBank uint8
+	programBanks   [4]uint8
+	spriteBanks    [8]uint16
+	bgBanks        [4]uint16
+	characterUpper uint8
+	lastBgWrite    bool
+	exRam          [mapper005ExRamSize]uint8
+
+	splitControl uint8
+	splitScroll  uint8
+	splitBank    uint8
+	inSplit      bool
+	splitTile    int
+	splitY       int
+
+	irqCompare     uint8
+	irqEnabled     bool
+	irqPending     bool
+	inFrame        bool
+	scanLine       int
+	lastPpuAddr    uint16
+	ppuAddrMatches int
+	lastPpuRead    int
+	nameTableReads int
+	spriteFetching bool
+	exAttr         uint8
+	cycle          int
+	tallSprites    bool
+	multiplicand   uint8
+	multiplier     uint8
+}
+
+func NewMapper005(board *Board) Mapper {
+	// ines headers rarely say how much prg ram there is, so give those games
+	// the most that the common boards have
+	if !board.ProgramRamSized {
+		board.ProgramRam = make([]uint8, mapper005ProgramRamSize)
+	}
+
+	mapper := &Mapper005{
+		board:         board,
+		programMode:   3,
+		characterMode: 3,
+	}
+	mapper.programBanks[3] = 0xFF
+	return mapper
+}
+
+func (mapper *Mapper005) ClockCpu() {
+	mapper.cycle++
+	if mapper.cycle-mapper.lastPpuRead > mapper005IdleCycles {
+		mapper.inFrame = false
+	}
+	mapper.audio.clock()
+}
+
+// WatchPpuAddr looks for the ppu reading the same name table address three
+// times in a row, which only happens at the start of each rendered scan line
+func (mapper *Mapper005) WatchPpuAddr(addr uint16) {
+	mapper.lastPpuRead = mapper.cycle
+	if addr == mapper.lastPpuAddr && addr >= 0x2000 && addr <= 0x2FFF {
+		mapper.ppuAddrMatches++
+		if mapper.ppuAddrMatches == 2 {
+			mapper.detectScanLine()
+		}
+	} else {
+		mapper.ppuAddrMatches = 0
+	}
+	mapper.lastPpuAddr = addr
+}
+
+func (mapper *Mapper005) detectScanLine() {
+	if !mapper.inFrame {
+		mapper.inFrame = true
+		mapper.scanLine = 0
+		mapper.irqPending = false
+	} else {
+		mapper.scanLine++
+		if mapper.scanLine == int(mapper.irqCompare) {
+			mapper.irqPending = true
+		}
+	}
+	mapper.nameTableReads = 0
+}
+
+func (mapper *Mapper005) WatchCpuWrite(addr uint16, data uint8) {
+	switch addr & 0xE007 {
+	case 0x2000:
+		mapper.tallSprites = data&0x20 > 0
+	case 0x2001:
+		if data&0x18 == 0 {
+			mapper.inFrame = false
+		}
+	}
+}
+
+func (mapper *Mapper005) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr == 0x5010 || addr == 0x5015:
+		return mapper.audio.read(addr)
+	case addr == 0x5204:
+		var status uint8
+		if mapper.irqPending {
+			status |= 0x80
+		}
+		if mapper.inFrame {
+			status |= 0x40
+		}
+		mapper.irqPending = false
+		return status, true
+	case addr == 0x5205:
+		return uint8(uint16(mapper.multiplicand) * uint16(mapper.multiplier)), true
+	case addr == 0x5206:
+		return uint8(uint16(mapper.multiplicand) * uint16(mapper.multiplier) >> 8), true
+	case addr >= 0x5C00 && addr <= 0x5FFF:
+		if mapper.exRamMode >= mapper005ExRamWritable {
+			return mapper.exRam[addr-0x5C00], true
+		}
+	case addr >= 0x6000:
+		// fetching the nmi vector is how the mmc5 knows a frame has ended
+		if addr == 0xFFFA || addr == 0xFFFB {
+			mapper.inFrame = false
+		}
+
+		offset, rom := mapper.programOffset(addr)
+		if !rom {
+			programRam := mapper.board.ProgramRam
+			if len(programRam) == 0 {
+				return 0, false
+			}
+			return programRam[offset%len(programRam)], true
+		}
+		programRom := mapper.board.ProgramRom
+		data := programRom[offset%len(programRom)]
+		mapper.audio.watchRead(addr, data)
+		return data, true
+	}
+	return 0, false
+}
+
+func (mapper *Mapper005) WriteCpu(addr uint16, data uint8) {
+	switch {
+	case addr >= 0x5000 && addr <= 0x5015:
+		mapper.audio.write(addr, data)
+	case addr == 0x5100:
+		mapper.programMode = data & 0x03
+	case addr == 0x5101:
+		mapper.characterMode = data & 0x03
+	case addr == 0x5102:
+		mapper.ramProtect1 = data & 0x03
+	case addr == 0x5103:
+		mapper.ramProtect2 = data & 0x03
+	case addr == 0x5104:
+		mapper.exRamMode = data & 0x03
+	case addr == 0x5105:
+		mapper.nameTables = data
+	case addr == 0x5106:
+		mapper.fillTile = data
+	case addr == 0x5107:
+		mapper.fillAttr = data & 0x03
+	case addr == 0x5113:
+		mapper.programRamBank = data
+	case addr >= 0x5114 && addr <= 0x5117:
+		mapper.programBanks[addr-0x5114] = data
+	case addr >= 0x5120 && addr <= 0x5127:
+		mapper.spriteBanks[addr-0x5120] = uint16(mapper.characterUpper)<<8 | uint16(data)
+		mapper.lastBgWrite = false
+	case addr >= 0x5128 && addr <= 0x512B:
+		mapper.bgBanks[addr-0x5128] = uint16(mapper.characterUpper)<<8 | uint16(data)
+		mapper.lastBgWrite = true
+	case addr == 0x5130:
+		mapper.characterUpper = data & 0x03
+	case addr == 0x5200:
+		mapper.splitControl = data
+	case addr == 0x5201:
+		mapper.splitScroll = data
+	case addr == 0x5202:
+		mapper.splitBank = data
+	case addr == 0x5203:
+		mapper.irqCompare = data
+	case addr == 0x5204:
+		mapper.irqEnabled = data&0x80 > 0
+	case addr == 0x5205:
+		mapper.multiplicand = data
+	case addr == 0x5206:
+		mapper.multiplier = data
+	case addr >= 0x5C00 && addr <= 0x5FFF:
+		mapper.writeExRam(addr, data)
+	case addr >= 0x6000:
+		offset, rom := mapper.programOffset(addr)
+		if !rom && len(mapper.board.ProgramRam) > 0 && mapper.ramProtect1 == 0x02 && mapper.ramProtect2 == 0x01 {
+			programRam := mapper.board.ProgramRam
+			programRam[offset%len(programRam)] = data
+		}
+	}
+}
+
+// writeExRam stores a cpu write to exram. while exram is used by the ppu it
+// can only be written during rendering, zero is stored otherwise
+func (mapper *Mapper005) writeExRam(addr uint16, data uint8) {
+	switch mapper.exRamMode {
+	case mapper005ExRamNameTable, mapper005ExRamAttributes:
+		if !mapper.inFrame {
+			data = 0
+		}
+		mapper.exRam[addr-0x5C00] = data
+	case mapper005ExRamWritable:
+		mapper.exRam[addr-0x5C00] = data
+	}
+}
+
+// programOffset maps $6000-$FFFF to an offset into prg rom or prg ram.
+// registers with bit 7 clear select ram, except for the last one which
+// always selects rom
+func (mapper *Mapper005) programOffset(addr uint16) (int, bool) {
+	if addr < 0x8000 {
+		bank := int(mapper.programRamBank & 0x07)
+		return bank*mapper005ProgramBankSize + int(addr)%mapper005ProgramBankSize, false
+	}
+
+	var index int
+	var banks int
+	switch mapper.programMode {
+	case 0:
+		index = 3
+		banks = 4
+	case 1:
+		index = 1 + 2*int((addr-0x8000)/0x4000)
+		banks = 2
+	case 2:
+		switch {
+		case addr < 0xC000:
+			index = 1
+			banks = 2
+		case addr < 0xE000:
+			index = 2
+			banks = 1
+		default:
+			index = 3
+			banks = 1
+		}
+	case 3:
+		index = int(addr-0x8000) / 0x2000
+		banks = 1
+	}
+
+	register := mapper.programBanks[index]
+	rom := register&0x80 > 0 || index == 3
+	bank := int(register & 0x7F)
+	if !rom {
+		bank &= 0x07
+	}
+	bank &^= banks - 1
+	offset := int(addr-0x8000) % (banks * mapper005ProgramBankSize)
+	return bank*mapper005ProgramBankSize + offset, rom
+}
+
+func (mapper *Mapper005) nameTableSource(addr uint16) uint8 {
+	index := (addr >> 10) & 0x03
+	return (mapper.nameTables >> (index * 2)) & 0x03
+}
+
+// fetchTile follows the background and sprite fetches by counting the name
+// table reads since the start of the scan line. the first 32 are the visible
+// tiles from the third one on, the next 16 are sprite fetches, and then the
+// first two tiles of the next line are fetched
+func (mapper *Mapper005) fetchTile(offset uint16) {
+	mapper.nameTableReads++
+	reads := mapper.nameTableReads
+	mapper.spriteFetching = reads >= mapper005SpriteFetchStart &&
+		reads <= mapper005SpriteFetchEnd
+	mapper.exAttr = mapper.exRam[offset]
+
+	mapper.inSplit = false
+	if !mapper.inFrame || mapper.spriteFetching ||
+		mapper.splitControl&mapper005SplitEnabled == 0 ||
+		mapper.exRamMode > mapper005ExRamAttributes {
+		return
+	}
+
+	tile := reads + 1
+	scanLine := mapper.scanLine
+	if reads > mapper005SpriteFetchEnd {
+		tile = reads - mapper005SpriteFetchEnd - 1
+		scanLine++
+	}
+	tile &= 0x1F
+
+	splitTiles := int(mapper.splitControl & mapper005SplitTiles)
+	if mapper.splitControl&mapper005SplitRightSide > 0 {
+		mapper.inSplit = tile >= splitTiles
+	} else {
+		mapper.inSplit = tile < splitTiles
+	}
+	mapper.splitTile = tile
+	mapper.splitY = (int(mapper.splitScroll) + scanLine) % 240
+}
+
+func (mapper *Mapper005) backgroundFetch() bool {
+	return mapper.inFrame && !mapper.spriteFetching
+}
+
+func (mapper *Mapper005) ReadNameTable(addr uint16, vram []uint8) uint8 {
+	offset := addr & 0x03FF
+	attribute := offset >= 0x03C0
+	if !attribute {
+		mapper.fetchTile(offset)
+	}
+
+	if mapper.inSplit && mapper.backgroundFetch() {
+		if !attribute {
+			return mapper.exRam[(mapper.splitY/8)*32+mapper.splitTile]
+		}
+		attr := mapper.exRam[0x03C0+(mapper.splitY/32)*8+mapper.splitTile/4]
+		shift := (mapper.splitY/16&0x01)*4 + (mapper.splitTile/2&0x01)*2
+		return (attr >> shift & 0x03) * 0x55
+	}
+
+	// extended attributes give every tile its own palette
+	if attribute && mapper.exRamMode == mapper005ExRamAttributes && mapper.backgroundFetch() {
+		return (mapper.exAttr >> 6) * 0x55
+	}
+
+	switch mapper.nameTableSource(addr) {
+	case mapper005NameTableVram0:
+		return vram[offset]
+	case mapper005NameTableVram1:
+		return vram[0x0400+offset]
+	case mapper005NameTableExRam:
+		if mapper.exRamMode <= mapper005ExRamAttributes {
+			return mapper.exRam[offset]
+		}
+		return 0
+	}
+	if attribute {
+		return mapper.fillAttr * 0x55
+	}
+	return mapper.fillTile
+}
+
+func (mapper *Mapper005) WriteNameTable(addr uint16, data uint8, vram []uint8) {
+	offset := addr & 0x03FF
+	switch mapper.nameTableSource(addr) {
+	case mapper005NameTableVram0:
+		vram[offset] = data
+	case mapper005NameTableVram1:
+		vram[0x0400+offset] = data
+	case mapper005NameTableExRam:
+		if mapper.exRamMode <= mapper005ExRamAttributes {
+			mapper.exRam[offset] = data
+		}
+	}
+}
+
+func (mapper *Mapper005) characterOffset(addr uint16) int {
+	if mapper.backgroundFetch() {
+		if mapper.inSplit {
+			// the split has its own vertical scroll
+			addr = addr&0x0FF8 | uint16(mapper.splitY&0x07)
+			return int(mapper.splitBank)*mapper005SplitBankSize + int(addr)
+		}
+		if mapper.exRamMode == mapper005ExRamAttributes {
+			bank := int(mapper.characterUpper)<<6 | int(mapper.exAttr&0x3F)
+			return bank*0x1000 + int(addr&0x0FFF)
+		}
+	}
+
+	// 8x16 sprites and the background have separate banks. with 8x8 sprites
+	// the sprite banks are used for everything
+	background := mapper.tallSprites &&
+		(mapper.backgroundFetch() || !mapper.inFrame && mapper.lastBgWrite)
+
+	size := 0x2000 >> mapper.characterMode
+	if background {
+		if mapper.characterMode > 0 {
+			addr &= 0x0FFF
+		}
+		register := ((int(addr)/size+1)*(size/0x0400) - 1) & 0x03
+		return int(mapper.bgBanks[register])*size + int(addr)%size
+	}
+	register := (int(addr)/size+1)*(size/0x0400) - 1
+	return int(mapper.spriteBanks[register])*size + int(addr)%size
+}
+
+func (mapper *Mapper005) ReadPpu(addr uint16) uint8 {
+	characterData := mapper.board.CharacterData
+	return characterData[mapper.characterOffset(addr)%len(characterData)]
+}
+
+func (mapper *Mapper005) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		characterData := mapper.board.CharacterData
+		characterData[mapper.characterOffset(addr)%len(characterData)] = data
+	}
+}
+
+func (mapper *Mapper005) Mirroring() Mirroring {
+	return mapper.board.Mirroring
+}
+
+func (mapper *Mapper005) Irq() bool {
+	return mapper.irqPending && mapper.irqEnabled || mapper.audio.irq()
+}
+
+func (mapper *Mapper005) AudioSample() float32 {
+	return mapper.audio.sample()
+}
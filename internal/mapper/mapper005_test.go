@@ -0,0 +1,85 @@
+package mapper
+
+import "testing"
+
+func newTestMapper005(board *Board) Mapper {
+	board.ProgramRom = bankedRom(16*mapper005ProgramBankSize, mapper005ProgramBankSize)
+	board.CharacterData = make([]uint8, 8192)
+	return NewMapper005(board)
+}
+
+func TestMapper005ProgramRamSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		board *Board
+		want  int
+	}{
+		{"ines", &Board{ProgramRam: make([]uint8, 8192)}, mapper005ProgramRamSize},
+		{"ines without prg ram", &Board{}, mapper005ProgramRamSize},
+		{"nes 2.0", &Board{ProgramRam: make([]uint8, 16384), ProgramRamSized: true}, 16384},
+		{"nes 2.0 without prg ram", &Board{ProgramRamSized: true}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			newTestMapper005(test.board)
+			if size := len(test.board.ProgramRam); size != test.want {
+				t.Errorf("%d bytes of prg ram, want %d", size, test.want)
+			}
+		})
+	}
+}
+
+func TestMapper005ProgramRam(t *testing.T) {
+	board := &Board{ProgramRam: make([]uint8, 16384), ProgramRamSized: true}
+	mapper := newTestMapper005(board)
+
+	// writes are ignored until both protect registers are set
+	mapper.WriteCpu(0x6000, 0x42)
+	if data := readCpu(t, mapper, 0x6000); data != 0 {
+		t.Errorf("read $%02X from write protected prg ram, want $00", data)
+	}
+	mapper.WriteCpu(0x5102, 0x02)
+	mapper.WriteCpu(0x5103, 0x01)
+	mapper.WriteCpu(0x5113, 1)
+	mapper.WriteCpu(0x6000, 0x42)
+
+	// banks past the end of the ram wrap around to its start
+	mapper.WriteCpu(0x5113, 3)
+	if data := readCpu(t, mapper, 0x6000); data != 0x42 {
+		t.Errorf("read $%02X from prg ram bank 3, want $42", data)
+	}
+
+	mapper = newTestMapper005(&Board{ProgramRamSized: true})
+	if _, ok := mapper.ReadCpu(0x6000); ok {
+		t.Error("prg ram readable on a board without any")
+	}
+}
+
+func TestMapper005ProgramBanks(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   uint8
+		writes [][2]uint16
+		banks  [4]uint8
+	}{
+		{"32k", 0, [][2]uint16{{0x5117, 0x05}}, [4]uint8{4, 5, 6, 7}},
+		{"16k", 1, [][2]uint16{{0x5115, 0x87}, {0x5117, 0x0B}}, [4]uint8{6, 7, 10, 11}},
+		{"16k and 8k", 2, [][2]uint16{{0x5115, 0x85}, {0x5116, 0x89}, {0x5117, 0x0F}}, [4]uint8{4, 5, 9, 15}},
+		{"8k", 3, [][2]uint16{{0x5114, 0x83}, {0x5115, 0x81}, {0x5116, 0x8C}, {0x5117, 0x02}}, [4]uint8{3, 1, 12, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := newTestMapper005(&Board{})
+			mapper.WriteCpu(0x5100, test.mode)
+			for _, write := range test.writes {
+				mapper.WriteCpu(write[0], uint8(write[1]))
+			}
+			for i, want := range test.banks {
+				addr := 0x8000 + uint16(i)*0x2000
+				if bank := readCpu(t, mapper, addr); bank != want {
+					t.Errorf("bank %d at $%04X, want %d", bank, addr, want)
+				}
+			}
+		})
+	}
+}
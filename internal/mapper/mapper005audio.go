@@ -0,0 +1,120 @@
+package mapper
+
+import "github.com/theaaronruss/nes-emulator/internal/sound"
+
+// the mmc5's own frame sequencer clocks envelopes and length counters at a
+// fixed 240hz
+const mapper005FrameSteps int = 7457
+
+// pcm control bit masks
+const (
+	mapper005PcmReadMode  uint8 = 0x01
+	mapper005PcmIrqEnable uint8 = 0x80
+)
+
+// mapper005Audio is the mmc5's two pulse channels and its 8-bit pcm channel,
+// which is either written directly or fed by reads from $8000-$BFFF
+type mapper005Audio struct {
+	pulse1     sound.Pulse
+	pulse2     sound.Pulse
+	pcm        uint8
+	pcmControl uint8
+	pcmIrq     bool
+	cycle      int
+}
+
+func (audio *mapper005Audio) clock() {
+	audio.cycle++
+	if audio.cycle%2 == 0 {
+		audio.pulse1.ClockTimer()
+		audio.pulse2.ClockTimer()
+	}
+	if audio.cycle >= mapper005FrameSteps {
+		audio.cycle = 0
+		audio.pulse1.ClockLength()
+		audio.pulse1.ClockEnvelope()
+		audio.pulse2.ClockLength()
+		audio.pulse2.ClockEnvelope()
+	}
+}
+
+func (audio *mapper005Audio) read(addr uint16) (uint8, bool) {
+	switch addr {
+	case 0x5010:
+		var status uint8
+		if audio.pcmIrq {
+			status |= 0x80
+		}
+		status |= audio.pcmControl & mapper005PcmReadMode
+		audio.pcmIrq = false
+		return status, true
+	case 0x5015:
+		var status uint8
+		if audio.pulse1.Length > 0 {
+			status |= 0x01
+		}
+		if audio.pulse2.Length > 0 {
+			status |= 0x02
+		}
+		return status, true
+	}
+	return 0, false
+}
+
+func (audio *mapper005Audio) write(addr uint16, data uint8) {
+	switch addr {
+	case 0x5000:
+		audio.pulse1.WriteControl(data)
+	case 0x5002:
+		audio.pulse1.WriteTimerLow(data)
+	case 0x5003:
+		audio.pulse1.WriteTimerHigh(data)
+	case 0x5004:
+		audio.pulse2.WriteControl(data)
+	case 0x5006:
+		audio.pulse2.WriteTimerLow(data)
+	case 0x5007:
+		audio.pulse2.WriteTimerHigh(data)
+	case 0x5010:
+		audio.pcmControl = data
+	case 0x5011:
+		if audio.pcmControl&mapper005PcmReadMode == 0 {
+			audio.writePcm(data)
+		}
+	case 0x5015:
+		audio.pulse1.SetEnabled(data&0x01 > 0)
+		audio.pulse2.SetEnabled(data&0x02 > 0)
+	}
+}
+
+// watchRead feeds prg rom reads into the pcm channel when it is in read mode
+func (audio *mapper005Audio) watchRead(addr uint16, data uint8) {
+	if audio.pcmControl&mapper005PcmReadMode > 0 && addr >= 0x8000 && addr < 0xC000 {
+		audio.writePcm(data)
+	}
+}
+
+// a zero can't be output, it raises the pcm irq instead
+func (audio *mapper005Audio) writePcm(data uint8) {
+	if data == 0 {
+		audio.pcmIrq = true
+		return
+	}
+	audio.pcm = data
+}
+
+func (audio *mapper005Audio) irq() bool {
+	return audio.pcmIrq && audio.pcmControl&mapper005PcmIrqEnable > 0
+}
+
+// sample mixes the pulses with the same curve as the apu's pulses and scales
+// the pcm channel to about the level of the dmc
+func (audio *mapper005Audio) sample() float32 {
+	pulseOut := sound.MixPulses(float32(audio.pulse1.Output()) + float32(audio.pulse2.Output()))
+
+	var pcmOut float32
+	if audio.pcm > 0 {
+		pcmOut = 159.79 / (22638/(float32(audio.pcm)/2) + 100)
+	}
+	return pulseOut + pcmOut
+}
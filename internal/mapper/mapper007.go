@@ -0,0 +1,61 @@
+package mapper
+
+const mapper007ProgramBankSize int = 32768
+
+// Mapper007 is AxROM. it switches all 32k of prg rom at once and picks which
+// name table is used for single screen mirroring. bus conflicts are only
+// emulated when the submapper says so since most games are on AOROM boards,
+// which don't have them
+type Mapper007 struct {
+	board *Board
+
+	programBank int
+	mirroring   Mirroring
+}
+
+func NewMapper007(board *Board) Mapper {
+	return &Mapper007{board: board, mirroring: MirrorSingleLower}
+}
+
+func (mapper *Mapper007) ReadCpu(addr uint16) (uint8, bool) {
+	if addr < 0x8000 {
+		return 0, false
+	}
+	offset := mapper.programBank*mapper007ProgramBankSize + int(addr-0x8000)
+	return mapper.board.ProgramRom[offset%len(mapper.board.ProgramRom)], true
+}
+
+func (mapper *Mapper007) WriteCpu(addr uint16, data uint8) {
+	if addr < 0x8000 {
+		return
+	}
+
+	if mapper.board.Submapper == submapperBusConflicts {
+		value, _ := mapper.ReadCpu(addr)
+		data &= value
+	}
+	mapper.programBank = int(data & 0x07)
+	if data&0x10 > 0 {
+		mapper.mirroring = MirrorSingleUpper
+	} else {
+		mapper.mirroring = MirrorSingleLower
+	}
+}
+
+func (mapper *Mapper007) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[int(addr)%len(mapper.board.CharacterData)]
+}
+
+func (mapper *Mapper007) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[int(addr)%len(mapper.board.CharacterData)] = data
+	}
+}
+
+func (mapper *Mapper007) Mirroring() Mirroring {
+	return mapper.mirroring
+}
+
+func (mapper *Mapper007) Irq() bool {
+	return false
+}
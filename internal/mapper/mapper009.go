@@ -0,0 +1,115 @@
+package mapper
+
+const (
+	mapper009ProgramBankSize   int = 8192
+	mapper009CharacterBankSize int = 4096
+)
+
+// Mapper009 is the mmc2. each half of the pattern table space has two chr
+// banks and a latch that picks between them. the latches flip when the ppu
+// reads tile $FD or $FE, so games can switch banks partway down the screen
+// just by placing those tiles. the mmc4 works the same way but switches 16k
+// of prg rom and has prg ram
+type Mapper009 struct {
+	board *Board
+	mmc4  bool
+
+	programBank    int
+	characterBanks [2][2]int
+	latches        [2]int
+	mirroring      Mirroring
+}
+
+func NewMapper009(board *Board) Mapper {
+	return &Mapper009{board: board, mirroring: board.Mirroring, latches: [2]int{1, 1}}
+}
+
+func (mapper *Mapper009) programOffset(addr uint16) int {
+	programRom := mapper.board.ProgramRom
+	bankSize := mapper009ProgramBankSize
+	if mapper.mmc4 {
+		bankSize *= 2
+	}
+
+	// everything after the switchable bank is fixed to the end of prg rom
+	offset := int(addr - 0x8000)
+	if offset < bankSize {
+		return (mapper.programBank*bankSize + offset) % len(programRom)
+	}
+	return len(programRom) - 0x8000 + offset
+}
+
+func (mapper *Mapper009) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0x8000:
+		return mapper.board.ProgramRom[mapper.programOffset(addr)], true
+	case addr >= 0x6000 && mapper.mmc4 && len(mapper.board.ProgramRam) > 0:
+		programRam := mapper.board.ProgramRam
+		return programRam[int(addr-0x6000)%len(programRam)], true
+	}
+	return 0, false
+}
+
+func (mapper *Mapper009) WriteCpu(addr uint16, data uint8) {
+	switch {
+	case addr >= 0xF000:
+		if data&0x01 == 0 {
+			mapper.mirroring = MirrorVertical
+		} else {
+			mapper.mirroring = MirrorHorizontal
+		}
+	case addr >= 0xB000:
+		register := int(addr-0xB000) / 0x1000
+		mapper.characterBanks[register/2][register%2] = int(data & 0x1F)
+	case addr >= 0xA000:
+		mapper.programBank = int(data & 0x0F)
+	case addr >= 0x6000 && addr < 0x8000 && mapper.mmc4 && len(mapper.board.ProgramRam) > 0:
+		programRam := mapper.board.ProgramRam
+		programRam[int(addr-0x6000)%len(programRam)] = data
+	}
+}
+
+func (mapper *Mapper009) characterOffset(addr uint16) int {
+	half := int(addr / 0x1000)
+	bank := mapper.characterBanks[half][mapper.latches[half]]
+	offset := bank*mapper009CharacterBankSize + int(addr)%mapper009CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+// updateLatch flips a latch after the fetch that triggered it. the mmc2 only
+// looks at one address in the lower pattern table while the mmc4 looks at
+// the whole tile like it does for the upper one
+func (mapper *Mapper009) updateLatch(addr uint16) {
+	half := int(addr/0x1000) & 0x01
+	tileAddr := addr & 0x0FF8
+	if half == 0 && !mapper.mmc4 {
+		tileAddr = addr & 0x0FFF
+	}
+
+	switch tileAddr {
+	case 0x0FD8:
+		mapper.latches[half] = 0
+	case 0x0FE8:
+		mapper.latches[half] = 1
+	}
+}
+
+func (mapper *Mapper009) ReadPpu(addr uint16) uint8 {
+	data := mapper.board.CharacterData[mapper.characterOffset(addr)]
+	mapper.updateLatch(addr)
+	return data
+}
+
+func (mapper *Mapper009) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(addr)] = data
+	}
+}
+
+func (mapper *Mapper009) Mirroring() Mirroring {
+	return mapper.mirroring
+}
+
+func (mapper *Mapper009) Irq() bool {
+	return false
+}
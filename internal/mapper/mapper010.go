@@ -0,0 +1,9 @@
+package mapper
+
+// NewMapper010 creates an mmc4, which only differs from the mmc2 in its prg
+// banking, prg ram and latch addresses
+func NewMapper010(board *Board) Mapper {
+	mapper := NewMapper009(board).(*Mapper009)
+	mapper.mmc4 = true
+	return mapper
+}
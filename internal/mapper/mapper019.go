@@ -0,0 +1,202 @@
+package mapper
+
+const (
+	mapper019ProgramBankSize   int = 8192
+	mapper019CharacterBankSize int = 1024
+
+	// banks from $E0 up select a page of the console's name table memory
+	mapper019VramBanks int = 0xE0
+)
+
+// prg bank bit masks
+const (
+	mapper019SoundDisable   uint8 = 0x40
+	mapper019LowVramOff     uint8 = 0x40
+	mapper019HighVramOff    uint8 = 0x80
+	mapper019ProgramBankBit uint8 = 0x3F
+)
+
+// Mapper019 is the namco 163. it can fill the pattern tables and name tables
+// with chr rom or with the console's own name table memory, and it has a cpu
+// cycle irq counter and wavetable sound
+type Mapper019 struct {
+	board *Board
+	audio mapper019Audio
+
+	programBanks   [3]uint8
+	characterBanks [8]uint8
+	nameTableBanks [4]uint8
+	ramProtect     uint8
+	irqCounter     uint16
+	irqEnabled     bool
+	irqPending     bool
+}
+
+func NewMapper019(board *Board) Mapper {
+	return &Mapper019{board: board}
+}
+
+func (mapper *Mapper019) ClockCpu() {
+	if mapper.irqEnabled && mapper.irqCounter < 0x7FFF {
+		mapper.irqCounter++
+		if mapper.irqCounter == 0x7FFF {
+			mapper.irqPending = true
+		}
+	}
+	mapper.audio.clock()
+}
+
+func (mapper *Mapper019) programOffset(addr uint16) int {
+	index := int(addr-0x8000) / mapper019ProgramBankSize
+	bank := len(mapper.board.ProgramRom)/mapper019ProgramBankSize - 1
+	if index < 3 {
+		bank = int(mapper.programBanks[index] & mapper019ProgramBankBit)
+	}
+	offset := bank*mapper019ProgramBankSize + int(addr)%mapper019ProgramBankSize
+	return offset % len(mapper.board.ProgramRom)
+}
+
+// programRamWritable checks the write protect register, which has to have
+// $4 in its upper bits and a clear bit for the 2k window being written
+func (mapper *Mapper019) programRamWritable(addr uint16) bool {
+	window := (addr - 0x6000) / 0x0800
+	return mapper.ramProtect&0xF0 == 0x40 && mapper.ramProtect&(1<<window) == 0
+}
+
+func (mapper *Mapper019) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0x8000:
+		return mapper.board.ProgramRom[mapper.programOffset(addr)], true
+	case addr >= 0x6000 && len(mapper.board.ProgramRam) > 0:
+		programRam := mapper.board.ProgramRam
+		return programRam[int(addr-0x6000)%len(programRam)], true
+	case addr >= 0x5800 && addr < 0x6000:
+		var data uint8
+		if mapper.irqEnabled {
+			data = 0x80
+		}
+		return data | uint8(mapper.irqCounter>>8), true
+	case addr >= 0x5000 && addr < 0x5800:
+		return uint8(mapper.irqCounter), true
+	case addr >= 0x4800 && addr < 0x5000:
+		return mapper.audio.read(), true
+	}
+	return 0, false
+}
+
+func (mapper *Mapper019) WriteCpu(addr uint16, data uint8) {
+	switch {
+	case addr >= 0x8000:
+		mapper.writeRegister(addr, data)
+	case addr >= 0x6000:
+		if len(mapper.board.ProgramRam) > 0 && mapper.programRamWritable(addr) {
+			programRam := mapper.board.ProgramRam
+			programRam[int(addr-0x6000)%len(programRam)] = data
+		}
+	case addr >= 0x5800:
+		mapper.irqCounter = mapper.irqCounter&0x00FF | uint16(data&0x7F)<<8
+		mapper.irqEnabled = data&0x80 > 0
+		mapper.irqPending = false
+	case addr >= 0x5000:
+		mapper.irqCounter = mapper.irqCounter&0x7F00 | uint16(data)
+		mapper.irqPending = false
+	case addr >= 0x4800:
+		mapper.audio.write(data)
+	}
+}
+
+func (mapper *Mapper019) writeRegister(addr uint16, data uint8) {
+	index := int(addr-0x8000) / 0x0800
+	switch {
+	case index < 8:
+		mapper.characterBanks[index] = data
+	case index < 12:
+		mapper.nameTableBanks[index-8] = data
+	case index < 15:
+		mapper.programBanks[index-12] = data
+		if index == 12 {
+			mapper.audio.disabled = data&mapper019SoundDisable > 0
+		}
+	default:
+		mapper.ramProtect = data
+		mapper.audio.selectAddress(data)
+	}
+}
+
+// characterVramOffset returns where a pattern table address lands in the name
+// table memory, or false if it is in chr rom
+func (mapper *Mapper019) characterVramOffset(addr uint16) (int, bool) {
+	bank := mapper.characterBanks[addr/0x0400]
+	if int(bank) < mapper019VramBanks {
+		return 0, false
+	}
+	disable := mapper019LowVramOff
+	if addr >= 0x1000 {
+		disable = mapper019HighVramOff
+	}
+	if mapper.programBanks[1]&disable > 0 {
+		return 0, false
+	}
+	return int(bank&0x01)*0x0400 + int(addr)%0x0400, true
+}
+
+func (mapper *Mapper019) characterOffset(bank uint8, addr uint16) int {
+	offset := int(bank)*mapper019CharacterBankSize + int(addr)%mapper019CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+func (mapper *Mapper019) ReadPpu(addr uint16) uint8 {
+	bank := mapper.characterBanks[addr/0x0400]
+	return mapper.board.CharacterData[mapper.characterOffset(bank, addr)]
+}
+
+func (mapper *Mapper019) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		bank := mapper.characterBanks[addr/0x0400]
+		mapper.board.CharacterData[mapper.characterOffset(bank, addr)] = data
+	}
+}
+
+func (mapper *Mapper019) ReadCharacterVram(addr uint16, vram []uint8) uint8 {
+	if offset, ok := mapper.characterVramOffset(addr); ok {
+		return vram[offset]
+	}
+	return mapper.ReadPpu(addr)
+}
+
+func (mapper *Mapper019) WriteCharacterVram(addr uint16, data uint8, vram []uint8) {
+	if offset, ok := mapper.characterVramOffset(addr); ok {
+		vram[offset] = data
+		return
+	}
+	mapper.WritePpu(addr, data)
+}
+
+func (mapper *Mapper019) ReadNameTable(addr uint16, vram []uint8) uint8 {
+	bank := mapper.nameTableBanks[(addr-0x2000)/0x0400%4]
+	if int(bank) >= mapper019VramBanks {
+		return vram[int(bank&0x01)*0x0400+int(addr)%0x0400]
+	}
+	return mapper.board.CharacterData[mapper.characterOffset(bank, addr)]
+}
+
+func (mapper *Mapper019) WriteNameTable(addr uint16, data uint8, vram []uint8) {
+	bank := mapper.nameTableBanks[(addr-0x2000)/0x0400%4]
+	if int(bank) >= mapper019VramBanks {
+		vram[int(bank&0x01)*0x0400+int(addr)%0x0400] = data
+	} else if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(bank, addr)] = data
+	}
+}
+
+func (mapper *Mapper019) Mirroring() Mirroring {
+	return mapper.board.Mirroring
+}
+
+func (mapper *Mapper019) Irq() bool {
+	return mapper.irqPending
+}
+
+func (mapper *Mapper019) AudioSample() float32 {
+	return mapper.audio.sample()
+}
@@ -0,0 +1,94 @@
+package mapper
+
+// a channel is updated every 15 cpu cycles, one at a time
+const mapper019ChannelCycles int = 15
+
+// a full volume channel spans about the same range as a full volume apu pulse
+const mapper019Level float32 = 0.15 / 225
+
+// the address port auto-increments after each data access when bit 7 is set
+const mapper019AutoIncrement uint8 = 0x80
+
+// mapper019Audio is the n163's wavetable sound. its 128 bytes of ram hold
+// 4-bit samples and, from $40 up, the registers of up to 8 channels. the chip
+// updates the enabled channels in turn and outputs one at a time, they are
+// averaged here instead
+type mapper019Audio struct {
+	ram      [128]uint8
+	address  uint8
+	cycle    int
+	channel  int
+	outputs  [8]float32
+	disabled bool
+}
+
+// enabledChannels reads the channel count from the top of the sound ram. the
+// enabled channels are the last ones
+func (audio *mapper019Audio) enabledChannels() int {
+	return int(audio.ram[0x7F]>>4&0x07) + 1
+}
+
+func (audio *mapper019Audio) selectAddress(data uint8) {
+	audio.address = data
+}
+
+func (audio *mapper019Audio) read() uint8 {
+	data := audio.ram[audio.address&0x7F]
+	audio.increment()
+	return data
+}
+
+func (audio *mapper019Audio) write(data uint8) {
+	audio.ram[audio.address&0x7F] = data
+	audio.increment()
+}
+
+func (audio *mapper019Audio) increment() {
+	if audio.address&mapper019AutoIncrement > 0 {
+		audio.address = mapper019AutoIncrement | (audio.address+1)&0x7F
+	}
+}
+
+func (audio *mapper019Audio) clock() {
+	audio.cycle++
+	if audio.cycle < mapper019ChannelCycles {
+		return
+	}
+	audio.cycle = 0
+
+	first := 8 - audio.enabledChannels()
+	if audio.channel < first {
+		audio.channel = 7
+	}
+	audio.updateChannel(audio.channel)
+	audio.channel--
+}
+
+func (audio *mapper019Audio) updateChannel(channel int) {
+	registers := audio.ram[0x40+channel*8:]
+	frequency := uint32(registers[0]) | uint32(registers[2])<<8 | uint32(registers[4]&0x03)<<16
+	phase := uint32(registers[1]) | uint32(registers[3])<<8 | uint32(registers[5])<<16
+	length := 256 - uint32(registers[4]&0xFC)
+
+	phase = (phase + frequency) % (length << 16)
+	registers[1] = uint8(phase)
+	registers[3] = uint8(phase >> 8)
+	registers[5] = uint8(phase >> 16)
+
+	index := uint8(phase>>16) + registers[6]
+	sample := audio.ram[index>>1] >> (index & 0x01 * 4) & 0x0F
+	volume := registers[7] & 0x0F
+	audio.outputs[channel] = (float32(sample) - 8) * float32(volume)
+}
+
+func (audio *mapper019Audio) sample() float32 {
+	if audio.disabled {
+		return 0
+	}
+	enabled := audio.enabledChannels()
+	var sum float32
+	for _, output := range audio.outputs[8-enabled:] {
+		sum += output
+	}
+	return sum / float32(enabled) * mapper019Level
+}
@@ -0,0 +1,243 @@
+package mapper
+
+const (
+	mapper021ProgramBankSize   int = 8192
+	mapper021CharacterBankSize int = 1024
+)
+
+// vrcWiring is the pair of cpu address lines that select bit 0 and bit 1 of
+// the register number behind each address range
+type vrcWiring struct {
+	line0 uint16
+	line1 uint16
+}
+
+// the wiring of each vrc4 board. vrc2a and vrc2c are wired like vrc4b and
+// vrc2b like vrc4f
+var (
+	vrc4aWiring = vrcWiring{0x0002, 0x0004}
+	vrc4bWiring = vrcWiring{0x0002, 0x0001}
+	vrc4cWiring = vrcWiring{0x0040, 0x0080}
+	vrc4dWiring = vrcWiring{0x0008, 0x0004}
+	vrc4eWiring = vrcWiring{0x0004, 0x0008}
+	vrc4fWiring = vrcWiring{0x0001, 0x0002}
+)
+
+// combine connects both wirings, for when the submapper doesn't say which
+// one a board has
+func (wiring vrcWiring) combine(other vrcWiring) vrcWiring {
+	return vrcWiring{wiring.line0 | other.line0, wiring.line1 | other.line1}
+}
+
+// Mapper021 covers konami's vrc2 and vrc4, which are used by mappers 21, 22,
+// 23 and 25. the boards differ in which cpu address lines select the four
+// registers behind each address range. the submapper says which wiring a
+// board has, and without one the possible wirings are combined, which works
+// since games only ever use addresses that are valid for their own board
+type Mapper021 struct {
+	board        *Board
+	irq          vrcIrq
+	wiring       vrcWiring
+	vrc2         bool
+	characterLow bool
+
+	programBanks   [2]int
+	programSwap    bool
+	characterBanks [8]int
+	mirroring      Mirroring
+}
+
+func newVrc(board *Board, wiring vrcWiring, vrc2 bool) *Mapper021 {
+	return &Mapper021{
+		board:     board,
+		wiring:    wiring,
+		vrc2:      vrc2,
+		mirroring: board.Mirroring,
+	}
+}
+
+// NewMapper021 creates a vrc4a (submapper 1) or vrc4c (submapper 2)
+func NewMapper021(board *Board) Mapper {
+	switch board.Submapper {
+	case 1:
+		return newVrc(board, vrc4aWiring, false)
+	case 2:
+		return newVrc(board, vrc4cWiring, false)
+	}
+	return newVrc(board, vrc4aWiring.combine(vrc4cWiring), false)
+}
+
+// NewMapper022 creates a vrc2a, which ignores the lowest bit of its chr bank
+// numbers
+func NewMapper022(board *Board) Mapper {
+	mapper := newVrc(board, vrc4bWiring, true)
+	mapper.characterLow = true
+	return mapper
+}
+
+// NewMapper023 creates a vrc4f (submapper 1), vrc4e (submapper 2) or vrc2b
+// (submapper 3)
+func NewMapper023(board *Board) Mapper {
+	switch board.Submapper {
+	case 1:
+		return newVrc(board, vrc4fWiring, false)
+	case 2:
+		return newVrc(board, vrc4eWiring, false)
+	case 3:
+		return newVrc(board, vrc4fWiring, true)
+	}
+	return newVrc(board, vrc4fWiring.combine(vrc4eWiring), false)
+}
+
+// NewMapper025 creates a vrc4b (submapper 1), vrc4d (submapper 2) or vrc2c
+// (submapper 3)
+func NewMapper025(board *Board) Mapper {
+	switch board.Submapper {
+	case 1:
+		return newVrc(board, vrc4bWiring, false)
+	case 2:
+		return newVrc(board, vrc4dWiring, false)
+	case 3:
+		return newVrc(board, vrc4bWiring, true)
+	}
+	return newVrc(board, vrc4bWiring.combine(vrc4dWiring), false)
+}
+
+func (mapper *Mapper021) ClockCpu() {
+	mapper.irq.clock()
+}
+
+func (mapper *Mapper021) register(addr uint16) int {
+	var register int
+	if addr&mapper.wiring.line0 > 0 {
+		register |= 0x01
+	}
+	if addr&mapper.wiring.line1 > 0 {
+		register |= 0x02
+	}
+	return register
+}
+
+func (mapper *Mapper021) programOffset(addr uint16) int {
+	bankCount := len(mapper.board.ProgramRom) / mapper021ProgramBankSize
+	var bank int
+	switch (addr - 0x8000) / 0x2000 {
+	case 0:
+		bank = mapper.programBanks[0]
+		if mapper.programSwap {
+			bank = bankCount - 2
+		}
+	case 1:
+		bank = mapper.programBanks[1]
+	case 2:
+		bank = bankCount - 2
+		if mapper.programSwap {
+			bank = mapper.programBanks[0]
+		}
+	case 3:
+		bank = bankCount - 1
+	}
+	offset := bank*mapper021ProgramBankSize + int(addr)%mapper021ProgramBankSize
+	return offset % len(mapper.board.ProgramRom)
+}
+
+func (mapper *Mapper021) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0x8000:
+		return mapper.board.ProgramRom[mapper.programOffset(addr)], true
+	case addr >= 0x6000 && len(mapper.board.ProgramRam) > 0:
+		programRam := mapper.board.ProgramRam
+		return programRam[int(addr-0x6000)%len(programRam)], true
+	}
+	return 0, false
+}
+
+func (mapper *Mapper021) WriteCpu(addr uint16, data uint8) {
+	if addr < 0x8000 {
+		if addr >= 0x6000 && len(mapper.board.ProgramRam) > 0 {
+			programRam := mapper.board.ProgramRam
+			programRam[int(addr-0x6000)%len(programRam)] = data
+		}
+		return
+	}
+
+	register := mapper.register(addr)
+	switch addr & 0xF000 {
+	case 0x8000:
+		mapper.programBanks[0] = int(data & 0x1F)
+	case 0x9000:
+		if register < 2 || mapper.vrc2 {
+			mapper.writeMirroring(data)
+		} else {
+			mapper.programSwap = data&0x02 > 0
+		}
+	case 0xA000:
+		mapper.programBanks[1] = int(data & 0x1F)
+	case 0xB000, 0xC000, 0xD000, 0xE000:
+		index := int((addr-0xB000)>>12)*2 + register>>1
+		bank := mapper.characterBanks[index]
+		if register&0x01 == 0 {
+			bank = bank&^0x0F | int(data&0x0F)
+		} else {
+			bank = bank&0x0F | int(data&0x1F)<<4
+		}
+		mapper.characterBanks[index] = bank
+	case 0xF000:
+		if mapper.vrc2 {
+			return
+		}
+		switch register {
+		case 0:
+			mapper.irq.writeLatchLow(data)
+		case 1:
+			mapper.irq.writeLatchHigh(data)
+		case 2:
+			mapper.irq.writeControl(data)
+		case 3:
+			mapper.irq.acknowledge()
+		}
+	}
+}
+
+func (mapper *Mapper021) writeMirroring(data uint8) {
+	if mapper.vrc2 {
+		data &= 0x01
+	}
+	switch data & 0x03 {
+	case 0:
+		mapper.mirroring = MirrorVertical
+	case 1:
+		mapper.mirroring = MirrorHorizontal
+	case 2:
+		mapper.mirroring = MirrorSingleLower
+	case 3:
+		mapper.mirroring = MirrorSingleUpper
+	}
+}
+
+func (mapper *Mapper021) characterOffset(addr uint16) int {
+	bank := mapper.characterBanks[addr/0x0400]
+	if mapper.characterLow {
+		bank >>= 1
+	}
+	offset := bank*mapper021CharacterBankSize + int(addr)%mapper021CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+func (mapper *Mapper021) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[mapper.characterOffset(addr)]
+}
+
+func (mapper *Mapper021) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(addr)] = data
+	}
+}
+
+func (mapper *Mapper021) Mirroring() Mirroring {
+	return mapper.mirroring
+}
+
+func (mapper *Mapper021) Irq() bool {
+	return mapper.irq.pending
+}
@@ -0,0 +1,112 @@
+package mapper
+
+import "testing"
+
+func TestMapper021Wiring(t *testing.T) {
+	tests := []struct {
+		name      string
+		newMapper func(*Board) Mapper
+		submapper int
+		wiring    vrcWiring
+		vrc2      bool
+	}{
+		{"vrc4a", NewMapper021, 1, vrcWiring{0x0002, 0x0004}, false},
+		{"vrc4c", NewMapper021, 2, vrcWiring{0x0040, 0x0080}, false},
+		{"mapper 21 vrc4a", NewMapper021, 0, vrcWiring{0x0002, 0x0004}, false},
+		{"mapper 21 vrc4c", NewMapper021, 0, vrcWiring{0x0040, 0x0080}, false},
+		{"vrc2a", NewMapper022, 0, vrcWiring{0x0002, 0x0001}, true},
+		{"vrc4f", NewMapper023, 1, vrcWiring{0x0001, 0x0002}, false},
+		{"vrc4e", NewMapper023, 2, vrcWiring{0x0004, 0x0008}, false},
+		{"vrc2b", NewMapper023, 3, vrcWiring{0x0001, 0x0002}, true},
+		{"mapper 23 vrc4f", NewMapper023, 0, vrcWiring{0x0001, 0x0002}, false},
+		{"mapper 23 vrc4e", NewMapper023, 0, vrcWiring{0x0004, 0x0008}, false},
+		{"vrc4b", NewMapper025, 1, vrcWiring{0x0002, 0x0001}, false},
+		{"vrc4d", NewMapper025, 2, vrcWiring{0x0008, 0x0004}, false},
+		{"vrc2c", NewMapper025, 3, vrcWiring{0x0002, 0x0001}, true},
+		{"mapper 25 vrc4b", NewMapper025, 0, vrcWiring{0x0002, 0x0001}, false},
+		{"mapper 25 vrc4d", NewMapper025, 0, vrcWiring{0x0008, 0x0004}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := test.newMapper(&Board{
+				ProgramRom:    bankedRom(16*mapper021ProgramBankSize, mapper021ProgramBankSize),
+				CharacterData: bankedRom(256*mapper021CharacterBankSize, mapper021CharacterBankSize),
+				Submapper:     test.submapper,
+			})
+			// write goes to one of the four registers at base, using the
+			// address lines of the board being tested
+			write := func(base uint16, register int, data uint8) {
+				addr := base
+				if register&0x01 > 0 {
+					addr |= test.wiring.line0
+				}
+				if register&0x02 > 0 {
+					addr |= test.wiring.line1
+				}
+				mapper.WriteCpu(addr, data)
+			}
+
+			write(0x8000, 0, 3)
+			write(0xA000, 0, 5)
+			if bank := readCpu(t, mapper, 0x8000); bank != 3 {
+				t.Errorf("bank %d at $8000, want 3", bank)
+			}
+			if bank := readCpu(t, mapper, 0xA000); bank != 5 {
+				t.Errorf("bank %d at $A000, want 5", bank)
+			}
+
+			write(0x9000, 0, 0x01)
+			if mirroring := mapper.Mirroring(); mirroring != MirrorHorizontal {
+				t.Errorf("mirroring %d, want horizontal", mirroring)
+			}
+
+			// each pair of registers holds the low and high bits of a chr bank
+			write(0xB000, 0, 0x04)
+			write(0xB000, 1, 0x02)
+			write(0xB000, 2, 0x06)
+			write(0xB000, 3, 0x03)
+			low, high := uint8(0x24), uint8(0x36)
+			if test.name == "vrc2a" {
+				low, high = 0x12, 0x1B
+			}
+			if bank := mapper.ReadPpu(0x0000); bank != low {
+				t.Errorf("chr bank %d at $0000, want %d", bank, low)
+			}
+			if bank := mapper.ReadPpu(0x0400); bank != high {
+				t.Errorf("chr bank %d at $0400, want %d", bank, high)
+			}
+
+			if test.vrc2 {
+				return
+			}
+
+			// the vrc4 swaps the fixed and switchable prg banks with the
+			// third register at $9000
+			write(0x9000, 2, 0x02)
+			if bank := readCpu(t, mapper, 0x8000); bank != 14 {
+				t.Errorf("bank %d at $8000 swapped, want 14", bank)
+			}
+			if bank := readCpu(t, mapper, 0xC000); bank != 3 {
+				t.Errorf("bank %d at $C000 swapped, want 3", bank)
+			}
+
+			// a latch of $FE in cycle mode overflows on the second clock
+			write(0xF000, 0, 0x0E)
+			write(0xF000, 1, 0x0F)
+			write(0xF000, 2, vrcIrqEnable|vrcIrqCycleMode)
+			mmc := mapper.(*Mapper021)
+			mmc.ClockCpu()
+			if mapper.Irq() {
+				t.Error("irq a clock early")
+			}
+			mmc.ClockCpu()
+			if !mapper.Irq() {
+				t.Error("no irq when the counter overflowed")
+			}
+			write(0xF000, 3, 0)
+			if mapper.Irq() {
+				t.Error("irq still pending after it was acknowledged")
+			}
+		})
+	}
+}
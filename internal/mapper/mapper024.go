@@ -0,0 +1,152 @@
+package mapper
+
+const (
+	mapper024ProgramBankSize   int = 8192
+	mapper024CharacterBankSize int = 1024
+)
+
+// banking control bit masks
+const (
+	mapper024Mirroring  uint8 = 0x0C
+	mapper024RamEnabled uint8 = 0x80
+)
+
+// Mapper024 is konami's vrc6. mapper 26 is the same board with cpu address
+// lines 0 and 1 swapped where they select the registers
+type Mapper024 struct {
+	board *Board
+	irq   vrcIrq
+	audio mapper024Audio
+
+	swapLines      bool
+	programBank16  int
+	programBank8   int
+	characterBanks [8]int
+	banking        uint8
+}
+
+func NewMapper024(board *Board) Mapper {
+	return &Mapper024{board: board}
+}
+
+func NewMapper026(board *Board) Mapper {
+	return &Mapper024{board: board, swapLines: true}
+}
+
+func (mapper *Mapper024) ClockCpu() {
+	mapper.irq.clock()
+	mapper.audio.clock()
+}
+
+func (mapper *Mapper024) programOffset(addr uint16) int {
+	var bank int
+	switch {
+	case addr < 0xC000:
+		bank = mapper.programBank16*2 + int(addr-0x8000)/mapper024ProgramBankSize
+	case addr < 0xE000:
+		bank = mapper.programBank8
+	default:
+		bank = len(mapper.board.ProgramRom)/mapper024ProgramBankSize - 1
+	}
+	offset := bank*mapper024ProgramBankSize + int(addr)%mapper024ProgramBankSize
+	return offset % len(mapper.board.ProgramRom)
+}
+
+func (mapper *Mapper024) programRamEnabled() bool {
+	return len(mapper.board.ProgramRam) > 0 && mapper.banking&mapper024RamEnabled > 0
+}
+
+func (mapper *Mapper024) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0x8000:
+		return mapper.board.ProgramRom[mapper.programOffset(addr)], true
+	case addr >= 0x6000 && mapper.programRamEnabled():
+		programRam := mapper.board.ProgramRam
+		return programRam[int(addr-0x6000)%len(programRam)], true
+	}
+	return 0, false
+}
+
+func (mapper *Mapper024) WriteCpu(addr uint16, data uint8) {
+	if addr < 0x8000 {
+		if addr >= 0x6000 && mapper.programRamEnabled() {
+			programRam := mapper.board.ProgramRam
+			programRam[int(addr-0x6000)%len(programRam)] = data
+		}
+		return
+	}
+
+	register := int(addr & 0x03)
+	if mapper.swapLines {
+		register = register>>1 | register&0x01<<1
+	}
+	switch addr & 0xF000 {
+	case 0x8000:
+		mapper.programBank16 = int(data & 0x0F)
+	case 0x9000:
+		if register == 3 {
+			mapper.audio.frequency = data
+		} else {
+			mapper.audio.pulse1.write(register, data)
+		}
+	case 0xA000:
+		mapper.audio.pulse2.write(register, data)
+	case 0xB000:
+		if register == 3 {
+			mapper.banking = data
+		} else {
+			mapper.audio.sawtooth.write(register, data)
+		}
+	case 0xC000:
+		mapper.programBank8 = int(data & 0x1F)
+	case 0xD000:
+		mapper.characterBanks[register] = int(data)
+	case 0xE000:
+		mapper.characterBanks[4+register] = int(data)
+	case 0xF000:
+		switch register {
+		case 0:
+			mapper.irq.writeLatch(data)
+		case 1:
+			mapper.irq.writeControl(data)
+		case 2:
+			mapper.irq.acknowledge()
+		}
+	}
+}
+
+func (mapper *Mapper024) characterOffset(addr uint16) int {
+	bank := mapper.characterBanks[addr/0x0400]
+	offset := bank*mapper024CharacterBankSize + int(addr)%mapper024CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+func (mapper *Mapper024) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[mapper.characterOffset(addr)]
+}
+
+func (mapper *Mapper024) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(addr)] = data
+	}
+}
+
+func (mapper *Mapper024) Mirroring() Mirroring {
+	switch (mapper.banking & mapper024Mirroring) >> 2 {
+	case 0:
+		return MirrorVertical
+	case 1:
+		return MirrorHorizontal
+	case 2:
+		return MirrorSingleLower
+	}
+	return MirrorSingleUpper
+}
+
+func (mapper *Mapper024) Irq() bool {
+	return mapper.irq.pending
+}
+
+func (mapper *Mapper024) AudioSample() float32 {
+	return mapper.audio.sample()
+}
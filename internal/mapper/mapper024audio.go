@@ -0,0 +1,148 @@
+package mapper
+
+// a vrc6 pulse at full volume is as loud as an apu pulse at full volume, so
+// each step of the channels' output is scaled to that level
+const mapper024Level float32 = 95.88 / (8128.0/15 + 100) / 15
+
+// frequency control bit masks
+const (
+	mapper024Halt   uint8 = 0x01
+	mapper024Shift4 uint8 = 0x02
+	mapper024Shift8 uint8 = 0x04
+)
+
+// channel bit masks
+const (
+	mapper024Digitize uint8 = 0x80
+	mapper024Enable   uint8 = 0x80
+)
+
+// mapper024Pulse is a vrc6 pulse channel. it has 16 duty steps and a mode
+// that outputs its volume constantly, which games use to play samples
+type mapper024Pulse struct {
+	control     uint8
+	timerPeriod uint16
+	timer       uint16
+	enabled     bool
+	dutyStep    uint8
+}
+
+func (pulse *mapper024Pulse) write(register int, data uint8) {
+	switch register {
+	case 0:
+		pulse.control = data
+	case 1:
+		pulse.timerPeriod = pulse.timerPeriod&0x0F00 | uint16(data)
+	case 2:
+		pulse.timerPeriod = pulse.timerPeriod&0x00FF | uint16(data&0x0F)<<8
+		pulse.enabled = data&mapper024Enable > 0
+		if !pulse.enabled {
+			pulse.dutyStep = 15
+		}
+	}
+}
+
+func (pulse *mapper024Pulse) clock(shift int) {
+	if !pulse.enabled {
+		return
+	}
+	if pulse.timer == 0 {
+		pulse.timer = pulse.timerPeriod >> shift
+		if pulse.dutyStep == 0 {
+			pulse.dutyStep = 15
+		} else {
+			pulse.dutyStep--
+		}
+	} else {
+		pulse.timer--
+	}
+}
+
+func (pulse *mapper024Pulse) output() uint8 {
+	if !pulse.enabled {
+		return 0
+	}
+	duty := pulse.control >> 4 & 0x07
+	if pulse.control&mapper024Digitize > 0 || pulse.dutyStep <= duty {
+		return pulse.control & 0x0F
+	}
+	return 0
+}
+
+// mapper024Sawtooth adds its rate to an accumulator on every other clock and
+// resets it after seven additions, the top 5 bits are the output
+type mapper024Sawtooth struct {
+	rate        uint8
+	timerPeriod uint16
+	timer       uint16
+	enabled     bool
+	step        uint8
+	accumulator uint8
+}
+
+func (saw *mapper024Sawtooth) write(register int, data uint8) {
+	switch register {
+	case 0:
+		saw.rate = data & 0x3F
+	case 1:
+		saw.timerPeriod = saw.timerPeriod&0x0F00 | uint16(data)
+	case 2:
+		saw.timerPeriod = saw.timerPeriod&0x00FF | uint16(data&0x0F)<<8
+		saw.enabled = data&mapper024Enable > 0
+		if !saw.enabled {
+			saw.step = 0
+			saw.accumulator = 0
+		}
+	}
+}
+
+func (saw *mapper024Sawtooth) clock(shift int) {
+	if !saw.enabled {
+		return
+	}
+	if saw.timer > 0 {
+		saw.timer--
+		return
+	}
+	saw.timer = saw.timerPeriod >> shift
+	saw.step++
+	if saw.step >= 14 {
+		saw.step = 0
+		saw.accumulator = 0
+	} else if saw.step%2 == 0 {
+		saw.accumulator += saw.rate
+	}
+}
+
+func (saw *mapper024Sawtooth) output() uint8 {
+	return saw.accumulator >> 3
+}
+
+// mapper024Audio is the vrc6's two pulse channels and its sawtooth channel
+type mapper024Audio struct {
+	pulse1    mapper024Pulse
+	pulse2    mapper024Pulse
+	sawtooth  mapper024Sawtooth
+	frequency uint8
+}
+
+func (audio *mapper024Audio) clock() {
+	if audio.frequency&mapper024Halt > 0 {
+		return
+	}
+	var shift int
+	switch {
+	case audio.frequency&mapper024Shift8 > 0:
+		shift = 8
+	case audio.frequency&mapper024Shift4 > 0:
+		shift = 4
+	}
+	audio.pulse1.clock(shift)
+	audio.pulse2.clock(shift)
+	audio.sawtooth.clock(shift)
+}
+
+func (audio *mapper024Audio) sample() float32 {
+	sum := int(audio.pulse1.output()) + int(audio.pulse2.output()) + int(audio.sawtooth.output())
+	return float32(sum) * mapper024Level
+}
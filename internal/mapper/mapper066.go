@@ -0,0 +1,62 @@
+package mapper
+
+const (
+	mapper066ProgramBankSize   int = 32768
+	mapper066CharacterBankSize int = 8192
+)
+
+// Mapper066 is GxROM, which switches 32k of prg rom and 8k of chr rom with a
+// single register
+type Mapper066 struct {
+	board *Board
+
+	programBank   int
+	characterBank int
+}
+
+func NewMapper066(board *Board) Mapper {
+	return &Mapper066{board: board}
+}
+
+func (mapper *Mapper066) ReadCpu(addr uint16) (uint8, bool) {
+	if addr < 0x8000 {
+		return 0, false
+	}
+	offset := mapper.programBank*mapper066ProgramBankSize + int(addr-0x8000)
+	return mapper.board.ProgramRom[offset%len(mapper.board.ProgramRom)], true
+}
+
+func (mapper *Mapper066) WriteCpu(addr uint16, data uint8) {
+	if addr < 0x8000 {
+		return
+	}
+
+	// bus conflict with the rom
+	value, _ := mapper.ReadCpu(addr)
+	data &= value
+	mapper.programBank = int(data>>4) & 0x03
+	mapper.characterBank = int(data & 0x03)
+}
+
+func (mapper *Mapper066) characterOffset(addr uint16) int {
+	offset := mapper.characterBank*mapper066CharacterBankSize + int(addr)%mapper066CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+func (mapper *Mapper066) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[mapper.characterOffset(addr)]
+}
+
+func (mapper *Mapper066) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(addr)] = data
+	}
+}
+
+func (mapper *Mapper066) Mirroring() Mirroring {
+	return mapper.board.Mirroring
+}
+
+func (mapper *Mapper066) Irq() bool {
+	return false
+}
@@ -0,0 +1,165 @@
+package mapper
+
+const (
+	mapper069ProgramBankSize   int = 8192
+	mapper069CharacterBankSize int = 1024
+)
+
+// $6000 bank bit masks
+const (
+	mapper069RamEnabled  uint8 = 0x80
+	mapper069RamSelected uint8 = 0x40
+	mapper069BankNumber  uint8 = 0x3F
+)
+
+// irq control bit masks
+const (
+	mapper069IrqEnabled     uint8 = 0x01
+	mapper069CounterEnabled uint8 = 0x80
+)
+
+// Mapper069 is sunsoft's fme-7 and the 5b, which is the same chip with sound.
+// a command register picks which of its 16 registers the parameter register
+// writes to. $6000-$7FFF can hold a bank of prg rom or prg ram
+type Mapper069 struct {
+	board *Board
+	audio mapper069Audio
+
+	command        uint8
+	characterBanks [8]int
+	ramBank        uint8
+	programBanks   [3]int
+	mirroring      Mirroring
+	irqControl     uint8
+	irqCounter     uint16
+	irqPending     bool
+}
+
+func NewMapper069(board *Board) Mapper {
+	return &Mapper069{
+		board:     board,
+		audio:     newMapper069Audio(),
+		mirroring: board.Mirroring,
+	}
+}
+
+func (mapper *Mapper069) ClockCpu() {
+	if mapper.irqControl&mapper069CounterEnabled > 0 {
+		mapper.irqCounter--
+		if mapper.irqCounter == 0xFFFF && mapper.irqControl&mapper069IrqEnabled > 0 {
+			mapper.irqPending = true
+		}
+	}
+	mapper.audio.clock()
+}
+
+func (mapper *Mapper069) programOffset(bank int, addr uint16) int {
+	offset := bank*mapper069ProgramBankSize + int(addr)%mapper069ProgramBankSize
+	return offset % len(mapper.board.ProgramRom)
+}
+
+func (mapper *Mapper069) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0xE000:
+		bank := len(mapper.board.ProgramRom)/mapper069ProgramBankSize - 1
+		return mapper.board.ProgramRom[mapper.programOffset(bank, addr)], true
+	case addr >= 0x8000:
+		bank := mapper.programBanks[(addr-0x8000)/0x2000]
+		return mapper.board.ProgramRom[mapper.programOffset(bank, addr)], true
+	case addr >= 0x6000:
+		bank := int(mapper.ramBank & mapper069BankNumber)
+		if mapper.ramBank&mapper069RamSelected == 0 {
+			return mapper.board.ProgramRom[mapper.programOffset(bank, addr)], true
+		}
+		if offset, ok := mapper.programRamOffset(addr); ok {
+			return mapper.board.ProgramRam[offset], true
+		}
+	}
+	return 0, false
+}
+
+// programRamOffset returns where $6000-$7FFF lands in prg ram, or false when
+// prg ram isn't mapped there
+func (mapper *Mapper069) programRamOffset(addr uint16) (int, bool) {
+	programRam := mapper.board.ProgramRam
+	if len(programRam) == 0 || mapper.ramBank&mapper069RamSelected == 0 || mapper.ramBank&mapper069RamEnabled == 0 {
+		return 0, false
+	}
+	bank := int(mapper.ramBank & mapper069BankNumber)
+	offset := bank*mapper069ProgramBankSize + int(addr-0x6000)
+	return offset % len(programRam), true
+}
+
+func (mapper *Mapper069) WriteCpu(addr uint16, data uint8) {
+	switch addr & 0xE000 {
+	case 0x6000:
+		if offset, ok := mapper.programRamOffset(addr); ok {
+			mapper.board.ProgramRam[offset] = data
+		}
+	case 0x8000:
+		mapper.command = data & 0x0F
+	case 0xA000:
+		mapper.writeParameter(data)
+	case 0xC000:
+		mapper.audio.selectRegister(data)
+	case 0xE000:
+		mapper.audio.write(data)
+	}
+}
+
+func (mapper *Mapper069) writeParameter(data uint8) {
+	switch command := mapper.command; {
+	case command < 0x08:
+		mapper.characterBanks[command] = int(data)
+	case command == 0x08:
+		mapper.ramBank = data
+	case command < 0x0C:
+		mapper.programBanks[command-0x09] = int(data & mapper069BankNumber)
+	case command == 0x0C:
+		switch data & 0x03 {
+		case 0:
+			mapper.mirroring = MirrorVertical
+		case 1:
+			mapper.mirroring = MirrorHorizontal
+		case 2:
+			mapper.mirroring = MirrorSingleLower
+		case 3:
+			mapper.mirroring = MirrorSingleUpper
+		}
+	case command == 0x0D:
+		mapper.irqControl = data
+		mapper.irqPending = false
+	case command == 0x0E:
+		mapper.irqCounter = mapper.irqCounter&0xFF00 | uint16(data)
+	case command == 0x0F:
+		mapper.irqCounter = mapper.irqCounter&0x00FF | uint16(data)<<8
+	}
+}
+
+func (mapper *Mapper069) characterOffset(addr uint16) int {
+	bank := mapper.characterBanks[addr/0x0400]
+	offset := bank*mapper069CharacterBankSize + int(addr)%mapper069CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+func (mapper *Mapper069) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[mapper.characterOffset(addr)]
+}
+
+func (mapper *Mapper069) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(addr)] = data
+	}
+}
+
+func (mapper *Mapper069) Mirroring() Mirroring {
+	return mapper.mirroring
+}
+
+func (mapper *Mapper069) Irq() bool {
+	return mapper.irqPending
+}
+
+func (mapper *Mapper069) AudioSample() float32 {
+	return mapper.audio.sample()
+}
@@ -0,0 +1,177 @@
+package mapper
+
+import "math"
+
+const (
+	// tones, noise and the envelope are all clocked every 16 cpu cycles
+	mapper069ClockDivider int = 16
+
+	// a channel at volume 12 is about as loud as an apu pulse at full volume
+	mapper069Level float32 = 0.42
+)
+
+// envelope shape bit masks
+const (
+	mapper069Hold      uint8 = 0x01
+	mapper069Alternate uint8 = 0x02
+	mapper069Attack    uint8 = 0x04
+	mapper069Continue  uint8 = 0x08
+)
+
+// volume bit masks
+const (
+	mapper069Volume   uint8 = 0x0F
+	mapper069Envelope uint8 = 0x10
+)
+
+// mapper069Amplitudes are the 32 output levels, 1.5db apart. fixed volumes
+// use every other one
+var mapper069Amplitudes = func() [32]float32 {
+	var amplitudes [32]float32
+	for level := 1; level < 32; level++ {
+		amplitudes[level] = float32(math.Pow(10, -float64(31-level)*1.5/20))
+	}
+	return amplitudes
+}()
+
+// mapper069Audio is the 5b, a licensed copy of the ay-3-8910 with three square
+// wave channels that share a noise generator and a volume envelope
+type mapper069Audio struct {
+	register  uint8
+	registers [16]uint8
+	cycle     int
+
+	toneCounters [3]uint16
+	toneOutputs  [3]bool
+
+	noiseCounter uint8
+	noiseShift   uint32
+	noiseHalf    bool
+
+	envelopeCounter   uint16
+	envelopeStep      int
+	envelopeAttack    bool
+	envelopeHolding   bool
+	envelopeHoldLevel int
+}
+
+func newMapper069Audio() mapper069Audio {
+	return mapper069Audio{noiseShift: 1}
+}
+
+func (audio *mapper069Audio) selectRegister(data uint8) {
+	audio.register = data & 0x0F
+}
+
+func (audio *mapper069Audio) write(data uint8) {
+	audio.registers[audio.register] = data
+	if audio.register == 0x0D {
+		audio.envelopeStep = 0
+		audio.envelopeAttack = data&mapper069Attack > 0
+		audio.envelopeHolding = false
+		audio.envelopeCounter = 0
+	}
+}
+
+func (audio *mapper069Audio) tonePeriod(channel int) uint16 {
+	return uint16(audio.registers[channel*2]) | uint16(audio.registers[channel*2+1]&0x0F)<<8
+}
+
+func (audio *mapper069Audio) clock() {
+	audio.cycle++
+	if audio.cycle < mapper069ClockDivider {
+		return
+	}
+	audio.cycle = 0
+
+	for channel := range audio.toneCounters {
+		audio.toneCounters[channel]++
+		if audio.toneCounters[channel] >= audio.tonePeriod(channel) {
+			audio.toneCounters[channel] = 0
+			audio.toneOutputs[channel] = !audio.toneOutputs[channel]
+		}
+	}
+
+	// the noise runs at half the rate of the tones
+	audio.noiseHalf = !audio.noiseHalf
+	if audio.noiseHalf {
+		audio.noiseCounter++
+		if audio.noiseCounter >= audio.registers[0x06]&0x1F {
+			audio.noiseCounter = 0
+			feedback := (audio.noiseShift ^ audio.noiseShift>>3) & 0x01
+			audio.noiseShift = audio.noiseShift>>1 | feedback<<16
+		}
+	}
+
+	audio.envelopeCounter++
+	period := uint16(audio.registers[0x0B]) | uint16(audio.registers[0x0C])<<8
+	if audio.envelopeCounter >= period {
+		audio.envelopeCounter = 0
+		audio.clockEnvelope()
+	}
+}
+
+// clockEnvelope steps through the 32 levels of the envelope. at the end of
+// each ramp it holds, repeats or turns around depending on its shape
+func (audio *mapper069Audio) clockEnvelope() {
+	if audio.envelopeHolding {
+		return
+	}
+	audio.envelopeStep++
+	if audio.envelopeStep < 32 {
+		return
+	}
+
+	shape := audio.registers[0x0D]
+	switch {
+	case shape&mapper069Continue == 0:
+		audio.envelopeHolding = true
+		audio.envelopeHoldLevel = 0
+	case shape&mapper069Hold > 0:
+		audio.envelopeHolding = true
+		audio.envelopeHoldLevel = 0
+		if audio.envelopeAttack != (shape&mapper069Alternate > 0) {
+			audio.envelopeHoldLevel = 31
+		}
+	default:
+		audio.envelopeStep = 0
+		if shape&mapper069Alternate > 0 {
+			audio.envelopeAttack = !audio.envelopeAttack
+		}
+	}
+}
+
+func (audio *mapper069Audio) envelopeLevel() int {
+	switch {
+	case audio.envelopeHolding:
+		return audio.envelopeHoldLevel
+	case audio.envelopeAttack:
+		return audio.envelopeStep
+	}
+	return 31 - audio.envelopeStep
+}
+
+func (audio *mapper069Audio) sample() float32 {
+	mixer := audio.registers[0x07]
+	noise := audio.noiseShift&0x01 > 0
+
+	var sum float32
+	for channel := range audio.toneOutputs {
+		toneOff := mixer&(0x01<<channel) > 0
+		noiseOff := mixer&(0x08<<channel) > 0
+		if !(audio.toneOutputs[channel] || toneOff) || !(noise || noiseOff) {
+			continue
+		}
+
+		volume := audio.registers[0x08+channel]
+		level := int(volume&mapper069Volume) * 2
+		if level > 0 {
+			level++
+		}
+		if volume&mapper069Envelope > 0 {
+			level = audio.envelopeLevel()
+		}
+		sum += mapper069Amplitudes[level]
+	}
+	return sum * mapper069Level
+}
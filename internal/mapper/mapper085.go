@@ -0,0 +1,153 @@
+package mapper
+
+const (
+	mapper085ProgramBankSize   int = 8192
+	mapper085CharacterBankSize int = 1024
+)
+
+// control bit masks
+const (
+	mapper085Mirroring  uint8 = 0x03
+	mapper085Silence    uint8 = 0x40
+	mapper085RamEnabled uint8 = 0x80
+)
+
+// Mapper085 is konami's vrc7. the second register in each address range is
+// selected by a4 on the vrc7a (submapper 2) and a3 on the vrc7b (submapper
+// 1), either one works when the submapper isn't known
+type Mapper085 struct {
+	board *Board
+	irq   vrcIrq
+	audio mapper085Audio
+
+	registerLine   uint16
+	programBanks   [3]int
+	characterBanks [8]int
+	control        uint8
+}
+
+func NewMapper085(board *Board) Mapper {
+	registerLine := uint16(0x0018)
+	switch board.Submapper {
+	case 1:
+		registerLine = 0x0008
+	case 2:
+		registerLine = 0x0010
+	}
+	return &Mapper085{board: board, registerLine: registerLine}
+}
+
+func (mapper *Mapper085) ClockCpu() {
+	mapper.irq.clock()
+	mapper.audio.clock()
+}
+
+func (mapper *Mapper085) programOffset(addr uint16) int {
+	index := int(addr-0x8000) / mapper085ProgramBankSize
+	bank := len(mapper.board.ProgramRom)/mapper085ProgramBankSize - 1
+	if index < 3 {
+		bank = mapper.programBanks[index]
+	}
+	offset := bank*mapper085ProgramBankSize + int(addr)%mapper085ProgramBankSize
+	return offset % len(mapper.board.ProgramRom)
+}
+
+func (mapper *Mapper085) programRamEnabled() bool {
+	return len(mapper.board.ProgramRam) > 0 && mapper.control&mapper085RamEnabled > 0
+}
+
+func (mapper *Mapper085) ReadCpu(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= 0x8000:
+		return mapper.board.ProgramRom[mapper.programOffset(addr)], true
+	case addr >= 0x6000 && mapper.programRamEnabled():
+		programRam := mapper.board.ProgramRam
+		return programRam[int(addr-0x6000)%len(programRam)], true
+	}
+	return 0, false
+}
+
+func (mapper *Mapper085) WriteCpu(addr uint16, data uint8) {
+	if addr < 0x8000 {
+		if addr >= 0x6000 && mapper.programRamEnabled() {
+			programRam := mapper.board.ProgramRam
+			programRam[int(addr-0x6000)%len(programRam)] = data
+		}
+		return
+	}
+
+	second := addr&mapper.registerLine > 0
+	switch addr & 0xF000 {
+	case 0x8000:
+		if second {
+			mapper.programBanks[1] = int(data & 0x3F)
+		} else {
+			mapper.programBanks[0] = int(data & 0x3F)
+		}
+	case 0x9000:
+		// the fm chip's ports are at $9010 and $9030 on every board
+		switch {
+		case addr&0x0030 == 0x0030:
+			mapper.audio.write(data)
+		case addr&0x0010 > 0:
+			mapper.audio.selectRegister(data)
+		case !second:
+			mapper.programBanks[2] = int(data & 0x3F)
+		}
+	case 0xA000, 0xB000, 0xC000, 0xD000:
+		index := int((addr-0xA000)>>12) * 2
+		if second {
+			index++
+		}
+		mapper.characterBanks[index] = int(data)
+	case 0xE000:
+		if second {
+			mapper.irq.writeLatch(data)
+		} else {
+			mapper.control = data
+			mapper.audio.silenced = data&mapper085Silence > 0
+		}
+	case 0xF000:
+		if second {
+			mapper.irq.acknowledge()
+		} else {
+			mapper.irq.writeControl(data)
+		}
+	}
+}
+
+func (mapper *Mapper085) characterOffset(addr uint16) int {
+	bank := mapper.characterBanks[addr/0x0400]
+	offset := bank*mapper085CharacterBankSize + int(addr)%mapper085CharacterBankSize
+	return offset % len(mapper.board.CharacterData)
+}
+
+func (mapper *Mapper085) ReadPpu(addr uint16) uint8 {
+	return mapper.board.CharacterData[mapper.characterOffset(addr)]
+}
+
+func (mapper *Mapper085) WritePpu(addr uint16, data uint8) {
+	if mapper.board.CharacterRam {
+		mapper.board.CharacterData[mapper.characterOffset(addr)] = data
+	}
+}
+
+func (mapper *Mapper085) Mirroring() Mirroring {
+	switch mapper.control & mapper085Mirroring {
+	case 0:
+		return MirrorVertical
+	case 1:
+		return MirrorHorizontal
+	case 2:
+		return MirrorSingleLower
+	}
+	return MirrorSingleUpper
+}
+
+func (mapper *Mapper085) Irq() bool {
+	return mapper.irq.pending
+}
+
+func (mapper *Mapper085) AudioSample() float32 {
+	return mapper.audio.sample()
+}
@@ -0,0 +1,347 @@
+package mapper
+
+import "math"
+
+const (
+	// the fm chip makes one sample for all of its channels every 36 cpu
+	// cycles
+	mapper085ClockDivider int     = 36
+	mapper085SampleRate   float64 = 1789773.0 / 36
+
+	// the envelope covers 48db, anything quieter is silent
+	mapper085MaxAttenuation float64 = 48
+
+	// a channel at full volume is about as loud as an apu pulse at full volume
+	mapper085Level float32 = 0.15
+
+	mapper085TremoloRate  float64 = 3.7
+	mapper085TremoloDepth float64 = 4.8
+	mapper085VibratoRate  float64 = 6.4
+	mapper085VibratoCents float64 = 13.75
+)
+
+// patch operator bit masks
+const (
+	mapper085Tremolo   uint8 = 0x80
+	mapper085Vibrato   uint8 = 0x40
+	mapper085Sustained uint8 = 0x20
+	mapper085KeyScale  uint8 = 0x10
+	mapper085Multiple  uint8 = 0x0F
+)
+
+// channel bit masks
+const (
+	mapper085ChannelSustain uint8 = 0x20
+	mapper085ChannelKeyOn   uint8 = 0x10
+)
+
+// envelope states
+const (
+	mapper085Off = iota
+	mapper085Attack
+	mapper085Decay
+	mapper085Sustain
+	mapper085Release
+)
+
+// mapper085Patches are the instruments built into the vrc7. instrument 0 is
+// the custom patch in registers $00-$07
+var mapper085Patches = [16][8]uint8{
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	{0x03, 0x21, 0x05, 0x06, 0xE8, 0x81, 0x42, 0x27},
+	{0x13, 0x41, 0x14, 0x0D, 0xD8, 0xF6, 0x23, 0x12},
+	{0x11, 0x11, 0x08, 0x08, 0xFA, 0xB2, 0x20, 0x12},
+	{0x31, 0x61, 0x0C, 0x07, 0xA8, 0x64, 0x61, 0x27},
+	{0x32, 0x21, 0x1E, 0x06, 0xE1, 0x76, 0x01, 0x28},
+	{0x02, 0x01, 0x06, 0x00, 0xA3, 0xE2, 0xF4, 0xF4},
+	{0x21, 0x61, 0x1D, 0x07, 0x82, 0x81, 0x11, 0x07},
+	{0x23, 0x21, 0x22, 0x17, 0xA2, 0x72, 0x01, 0x17},
+	{0x35, 0x11, 0x25, 0x00, 0x40, 0x73, 0x72, 0x01},
+	{0xB5, 0x01, 0x0F, 0x0F, 0xA8, 0xA5, 0x51, 0x02},
+	{0x17, 0xC1, 0x24, 0x07, 0xF8, 0xF8, 0x22, 0x12},
+	{0x71, 0x23, 0x11, 0x06, 0x65, 0x74, 0x18, 0x16},
+	{0x01, 0x02, 0xD3, 0x05, 0xC9, 0x95, 0x03, 0x02},
+	{0x61, 0x63, 0x0C, 0x00, 0x94, 0xC0, 0x33, 0xF6},
+	{0x21, 0x72, 0x0D, 0x00, 0xC1, 0xD5, 0x56, 0x06},
+}
+
+// frequency multiples selected by the low 4 bits of an operator's flags
+var mapper085Multiples = [16]float64{
+	0.5, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 12, 12, 15, 15,
+}
+
+// key scale attenuation in db at 6db per octave for the top 4 bits of the
+// f-number in octave 7. the other settings scale it down
+var mapper085KeyScaleLevels = [16]float64{
+	0, 18, 24, 27.75, 30, 32.25, 33.75, 35.25,
+	36, 37.5, 38.25, 39, 39.75, 40.5, 41.25, 42,
+}
+
+var mapper085KeyScaleFactors = [4]float64{0, 0.25, 0.5, 1}
+
+// mapper085Operator is one of a channel's two operators, a sine oscillator
+// with its own envelope
+type mapper085Operator struct {
+	phase    float64
+	envelope float64
+	state    int
+	previous [2]float64
+}
+
+func (op *mapper085Operator) keyOn() {
+	if op.state == mapper085Off {
+		op.envelope = mapper085MaxAttenuation
+	}
+	op.phase = 0
+	op.state = mapper085Attack
+}
+
+func (op *mapper085Operator) keyOff() {
+	if op.state != mapper085Off {
+		op.state = mapper085Release
+	}
+}
+
+// effectiveRate combines a 4-bit envelope rate with the key scale rate
+func mapper085EffectiveRate(rate uint8, keyScale int) int {
+	return min(int(rate)*4+keyScale, 63)
+}
+
+// mapper085DecayStep is how many db a decay rate adds each sample. a full
+// decay takes about 20 seconds at the slowest rate and halves every 4 steps
+func mapper085DecayStep(rate uint8, keyScale int) float64 {
+	if rate == 0 {
+		return 0
+	}
+	seconds := 41 / math.Exp2(float64(mapper085EffectiveRate(rate, keyScale))/4)
+	return mapper085MaxAttenuation / (seconds * mapper085SampleRate)
+}
+
+// clockEnvelope moves the envelope one sample along using the operator's half
+// of the patch, which is selected by index
+func (op *mapper085Operator) clockEnvelope(patch *[8]uint8, index int, keyScale int, sustainOn bool) {
+	attack := patch[4+index] >> 4
+	decay := patch[4+index] & 0x0F
+	sustainLevel := float64(patch[6+index]>>4) * 3
+	release := patch[6+index] & 0x0F
+
+	switch op.state {
+	case mapper085Attack:
+		if attack == 0 {
+			return
+		}
+		rate := mapper085EffectiveRate(attack, keyScale)
+		if rate >= 60 {
+			op.envelope = 0
+		} else {
+			// the attack is exponential, it takes about 3.5 seconds from
+			// silence at the slowest rate
+			samples := 7 / math.Exp2(float64(rate)/4) * mapper085SampleRate
+			op.envelope *= math.Pow(0.1/mapper085MaxAttenuation, 1/samples)
+		}
+		if op.envelope < 0.1 {
+			op.envelope = 0
+			op.state = mapper085Decay
+		}
+	case mapper085Decay:
+		op.envelope += mapper085DecayStep(decay, keyScale)
+		if op.envelope >= sustainLevel {
+			op.envelope = sustainLevel
+			op.state = mapper085Sustain
+		}
+	case mapper085Sustain:
+		// percussive patches keep fading while the key is held
+		if patch[index]&mapper085Sustained == 0 {
+			op.envelope += mapper085DecayStep(release, keyScale)
+		}
+	case mapper085Release:
+		if sustainOn {
+			release = 5
+		}
+		op.envelope += mapper085DecayStep(release, keyScale)
+	case mapper085Off:
+		return
+	}
+
+	if op.envelope >= mapper085MaxAttenuation {
+		op.envelope = mapper085MaxAttenuation
+		if op.state != mapper085Attack {
+			op.state = mapper085Off
+		}
+	}
+}
+
+func (op *mapper085Operator) clockPhase(step float64) {
+	op.phase += step
+	op.phase -= math.Floor(op.phase)
+}
+
+// output is the operator's sine at its phase shifted by modulation, which is in
+// cycles. the half-rectified waveform drops the negative half
+func (op *mapper085Operator) output(modulation float64, rectified bool, attenuation float64) float64 {
+	if op.state == mapper085Off || attenuation >= mapper085MaxAttenuation {
+		return 0
+	}
+	out := math.Sin(2 * math.Pi * (op.phase + modulation))
+	if rectified && out < 0 {
+		out = 0
+	}
+	return out * math.Pow(10, -attenuation/20)
+}
+
+// mapper085Channel is a voice made of a modulator operator that shifts the
+// phase of a carrier operator
+type mapper085Channel struct {
+	frequency  uint16
+	octave     uint8
+	sustain    bool
+	key        bool
+	instrument uint8
+	volume     uint8
+
+	modulator mapper085Operator
+	carrier   mapper085Operator
+}
+
+func (channel *mapper085Channel) writeControl(data uint8) {
+	channel.frequency = channel.frequency&0x00FF | uint16(data&0x01)<<8
+	channel.octave = data >> 1 & 0x07
+	channel.sustain = data&mapper085ChannelSustain > 0
+
+	key := data&mapper085ChannelKeyOn > 0
+	if key && !channel.key {
+		channel.modulator.keyOn()
+		channel.carrier.keyOn()
+	} else if !key && channel.key {
+		channel.modulator.keyOff()
+		channel.carrier.keyOff()
+	}
+	channel.key = key
+}
+
+// keyScale is the amount an operator's envelope rates are raised for higher
+// notes
+func (channel *mapper085Channel) keyScale(flags uint8) int {
+	keyScale := int(channel.octave)<<1 | int(channel.frequency>>8)
+	if flags&mapper085KeyScale == 0 {
+		keyScale >>= 2
+	}
+	return keyScale
+}
+
+// keyScaleLevel is the attenuation for higher notes in db
+func (channel *mapper085Channel) keyScaleLevel(setting uint8) float64 {
+	level := mapper085KeyScaleLevels[channel.frequency>>5] - 6*float64(7-channel.octave)
+	return max(level, 0) * mapper085KeyScaleFactors[setting]
+}
+
+// mapper085Audio is the vrc7's fm chip, a cut down ym2413 with six melodic
+// channels and 15 fixed instruments
+type mapper085Audio struct {
+	register uint8
+	custom   [8]uint8
+	channels [6]mapper085Channel
+	cycle    int
+	tremolo  float64
+	vibrato  float64
+	output   float32
+	silenced bool
+}
+
+func (audio *mapper085Audio) selectRegister(data uint8) {
+	audio.register = data
+}
+
+func (audio *mapper085Audio) write(data uint8) {
+	register := audio.register
+	switch {
+	case register < 0x08:
+		audio.custom[register] = data
+	case register >= 0x10 && register < 0x16:
+		channel := &audio.channels[register-0x10]
+		channel.frequency = channel.frequency&0x0100 | uint16(data)
+	case register >= 0x20 && register < 0x26:
+		audio.channels[register-0x20].writeControl(data)
+	case register >= 0x30 && register < 0x36:
+		channel := &audio.channels[register-0x30]
+		channel.instrument = data >> 4
+		channel.volume = data & 0x0F
+	}
+}
+
+func (audio *mapper085Audio) patch(instrument uint8) *[8]uint8 {
+	if instrument == 0 {
+		return &audio.custom
+	}
+	return &mapper085Patches[instrument]
+}
+
+func (audio *mapper085Audio) clock() {
+	audio.cycle++
+	if audio.cycle < mapper085ClockDivider {
+		return
+	}
+	audio.cycle = 0
+
+	audio.tremolo += mapper085TremoloRate / mapper085SampleRate
+	audio.tremolo -= math.Floor(audio.tremolo)
+	audio.vibrato += mapper085VibratoRate / mapper085SampleRate
+	audio.vibrato -= math.Floor(audio.vibrato)
+	tremolo := mapper085TremoloDepth * (1 - math.Cos(2*math.Pi*audio.tremolo)) / 2
+	vibrato := math.Exp2(mapper085VibratoCents / 1200 * math.Sin(2*math.Pi*audio.vibrato))
+
+	var sum float64
+	for i := range audio.channels {
+		sum += audio.clockChannel(&audio.channels[i], tremolo, vibrato)
+	}
+	audio.output = float32(sum)
+}
+
+func (audio *mapper085Audio) clockChannel(channel *mapper085Channel, tremolo float64, vibrato float64) float64 {
+	patch := audio.patch(channel.instrument)
+	step := float64(channel.frequency) * math.Exp2(float64(channel.octave)-1) / (1 << 18)
+
+	var out float64
+	modulation := 0.0
+	for index, op := range [2]*mapper085Operator{&channel.modulator, &channel.carrier} {
+		flags := patch[index]
+		op.clockEnvelope(patch, index, channel.keyScale(flags), channel.sustain)
+
+		opStep := step * mapper085Multiples[flags&mapper085Multiple]
+		if flags&mapper085Vibrato > 0 {
+			opStep *= vibrato
+		}
+		op.clockPhase(opStep)
+
+		attenuation := op.envelope + channel.keyScaleLevel(patch[2+index]>>6)
+		if flags&mapper085Tremolo > 0 {
+			attenuation += tremolo
+		}
+
+		if index == 0 {
+			// the modulator's level comes from the patch and it feeds the
+			// average of its last two outputs back into its own phase
+			attenuation += float64(patch[2]&0x3F) * 0.75
+			feedback := patch[3] & 0x07
+			var self float64
+			if feedback > 0 {
+				self = (op.previous[0] + op.previous[1]) / 2 * math.Exp2(float64(feedback)-1) / 32
+			}
+			modOut := op.output(self, patch[3]&0x08 > 0, attenuation)
+			op.previous[1] = op.previous[0]
+			op.previous[0] = modOut
+			modulation = modOut * 2
+		} else {
+			attenuation += float64(channel.volume) * 3
+			out = op.output(modulation, patch[3]&0x10 > 0, attenuation)
+		}
+	}
+	return out
+}
+
+func (audio *mapper085Audio) sample() float32 {
+	if audio.silenced {
+		return 0
+	}
+	return audio.output * mapper085Level
+}
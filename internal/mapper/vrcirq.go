@@ -0,0 +1,70 @@
+package mapper
+
+// irq control bit masks
+const (
+	vrcIrqEnableAfterAck uint8 = 0x01
+	vrcIrqEnable         uint8 = 0x02
+	vrcIrqCycleMode      uint8 = 0x04
+)
+
+// vrcIrq is the irq counter shared by konami's vrc chips. it counts up to
+// $FF and then reloads from the latch, clocked either every cpu cycle or
+// about once per scan line by a prescaler that divides cpu cycles by 113.667
+type vrcIrq struct {
+	latch     uint8
+	counter   uint8
+	prescaler int
+	control   uint8
+	pending   bool
+}
+
+func (irq *vrcIrq) writeLatchLow(data uint8) {
+	irq.latch = irq.latch&0xF0 | data&0x0F
+}
+
+func (irq *vrcIrq) writeLatchHigh(data uint8) {
+	irq.latch = irq.latch&0x0F | data<<4
+}
+
+func (irq *vrcIrq) writeLatch(data uint8) {
+	irq.latch = data
+}
+
+func (irq *vrcIrq) writeControl(data uint8) {
+	irq.control = data
+	irq.pending = false
+	if irq.control&vrcIrqEnable > 0 {
+		irq.counter = irq.latch
+		irq.prescaler = 341
+	}
+}
+
+func (irq *vrcIrq) acknowledge() {
+	irq.pending = false
+	if irq.control&vrcIrqEnableAfterAck > 0 {
+		irq.control |= vrcIrqEnable
+	} else {
+		irq.control &^= vrcIrqEnable
+	}
+}
+
+func (irq *vrcIrq) clock() {
+	if irq.control&vrcIrqEnable == 0 {
+		return
+	}
+
+	if irq.control&vrcIrqCycleMode == 0 {
+		irq.prescaler -= 3
+		if irq.prescaler > 0 {
+			return
+		}
+		irq.prescaler += 341
+	}
+
+	if irq.counter == 0xFF {
+		irq.counter = irq.latch
+		irq.pending = true
+	} else {
+		irq.counter++
+	}
+}
@@ -0,0 +1,664 @@
+package nes
+
+import "github.com/theaaronruss/nes-emulator/internal/sound"
+
+// apu status bit masks
+const (
+	pulse1StatusBitMask    uint8 = 0x01
+	pulse2StatusBitMask    uint8 = 0x02
+	triangleStatusBitMask  uint8 = 0x04
+	noiseStatusBitMask     uint8 = 0x08
+	dmcStatusBitMask       uint8 = 0x10
+	frameIrqStatusBitMask  uint8 = 0x40
+	dmcIrqStatusBitMask    uint8 = 0x80
+	frameModeBitMask       uint8 = 0x80
+	frameIrqInhibitBitMask uint8 = 0x40
+)
+
+// frame counter steps, in cpu cycles
+const (
+	frameStep1     int = 7457
+	frameStep2     int = 14913
+	frameStep3     int = 22371
+	frameStep4     int = 29829
+	frameStep5     int = 37281
+	fourStepPeriod int = 29830
+	fiveStepPeriod int = 37282
+)
+
+const dmcSampleBaseAddr uint16 = 0xC000
+
+type AudioChannel int
+
+const (
+	ChannelPulse1 AudioChannel = iota
+	ChannelPulse2
+	ChannelTriangle
+	ChannelNoise
+	ChannelDmc
+	ChannelExpansion
+	AudioChannelCount
+)
+
+var audioChannelNames = [AudioChannelCount]string{
+	"pulse 1", "pulse 2", "triangle", "noise", "dmc", "expansion",
+}
+
+func (channel AudioChannel) String() string {
+	return audioChannelNames[channel]
+}
+
+var triangleTable = [32]uint8{
+	15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+var noisePeriodTable = [16]uint16{
+	4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068,
+}
+
+var dmcRateTable = [16]uint16{
+	428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54,
+}
+
+// pulse is the apu's pulse channel, which adds a sweep unit to the shared one
+type pulse struct {
+	sound.Pulse
+	onesCompl bool
+
+	sweepEnabled bool
+	sweepPeriod  uint8
+	sweepNegate  bool
+	sweepShift   uint8
+	sweepDivider uint8
+	sweepReload  bool
+}
+
+func (pulse *pulse) writeSweep(data uint8) {
+	pulse.sweepEnabled = data&0x80 > 0
+	pulse.sweepPeriod = (data >> 4) & 0x07
+	pulse.sweepNegate = data&0x08 > 0
+	pulse.sweepShift = data & 0x07
+	pulse.sweepReload = true
+}
+
+func (pulse *pulse) sweepTarget() uint16 {
+	change := pulse.TimerPeriod >> pulse.sweepShift
+	if !pulse.sweepNegate {
+		return pulse.TimerPeriod + change
+	}
+
+	// pulse 1 negates with ones' complement, so it subtracts one extra
+	if pulse.onesCompl {
+		change++
+	}
+	if change > pulse.TimerPeriod {
+		return 0
+	}
+	return pulse.TimerPeriod - change
+}
+
+func (pulse *pulse) sweepMuted() bool {
+	return pulse.TimerPeriod < 8 || pulse.sweepTarget() > 0x07FF
+}
+
+func (pulse *pulse) clockSweep() {
+	if pulse.sweepDivider == 0 && pulse.sweepEnabled &&
+		pulse.sweepShift > 0 && !pulse.sweepMuted() {
+		pulse.TimerPeriod = pulse.sweepTarget()
+	}
+
+	if pulse.sweepDivider == 0 || pulse.sweepReload {
+		pulse.sweepDivider = pulse.sweepPeriod
+		pulse.sweepReload = false
+	} else {
+		pulse.sweepDivider--
+	}
+}
+
+func (pulse *pulse) output() uint8 {
+	if pulse.sweepMuted() {
+		return 0
+	}
+	return pulse.Output()
+}
+
+type triangle struct {
+	enabled      bool
+	step         uint8
+	timer        uint16
+	timerPeriod  uint16
+	length       uint8
+	control      bool
+	linear       uint8
+	linearPeriod uint8
+	linearReload bool
+}
+
+func (tri *triangle) writeControl(data uint8) {
+	tri.control = data&0x80 > 0
+	tri.linearPeriod = data & 0x7F
+}
+
+func (tri *triangle) writeTimerLow(data uint8) {
+	tri.timerPeriod &= 0xFF00
+	tri.timerPeriod |= uint16(data)
+}
+
+func (tri *triangle) writeTimerHigh(data uint8) {
+	tri.timerPeriod &= 0x00FF
+	tri.timerPeriod |= uint16(data&0x07) << 8
+	if tri.enabled {
+		tri.length = sound.LengthTable[data>>3]
+	}
+	tri.linearReload = true
+}
+
+func (tri *triangle) setEnabled(enabled bool) {
+	tri.enabled = enabled
+	if !enabled {
+		tri.length = 0
+	}
+}
+
+func (tri *triangle) clockTimer() {
+	if tri.timer > 0 {
+		tri.timer--
+		return
+	}
+
+	tri.timer = tri.timerPeriod
+	if tri.length > 0 && tri.linear > 0 {
+		tri.step = (tri.step + 1) % 32
+	}
+}
+
+func (tri *triangle) clockLength() {
+	if !tri.control && tri.length > 0 {
+		tri.length--
+	}
+}
+
+func (tri *triangle) clockLinear() {
+	if tri.linearReload {
+		tri.linear = tri.linearPeriod
+	} else if tri.linear > 0 {
+		tri.linear--
+	}
+
+	if !tri.control {
+		tri.linearReload = false
+	}
+}
+
+func (tri *triangle) output() uint8 {
+	// ultrasonic periods are output as the middle of the waveform since
+	// that's what the real hardware averages out to after filtering
+	if tri.timerPeriod < 2 {
+		return 7
+	}
+	return triangleTable[tri.step]
+}
+
+type noise struct {
+	enabled     bool
+	shortMode   bool
+	shift       uint16
+	timer       uint16
+	timerPeriod uint16
+	length      uint8
+	lengthHalt  bool
+	env         sound.Envelope
+}
+
+func (noise *noise) writeControl(data uint8) {
+	noise.lengthHalt = data&0x20 > 0
+	noise.env.Write(data)
+}
+
+func (noise *noise) writePeriod(data uint8) {
+	noise.shortMode = data&0x80 > 0
+	noise.timerPeriod = noisePeriodTable[data&0x0F]
+}
+
+func (noise *noise) writeLength(data uint8) {
+	if noise.enabled {
+		noise.length = sound.LengthTable[data>>3]
+	}
+	noise.env.Restart()
+}
+
+func (noise *noise) setEnabled(enabled bool) {
+	noise.enabled = enabled
+	if !enabled {
+		noise.length = 0
+	}
+}
+
+func (noise *noise) clockTimer() {
+	if noise.timer > 0 {
+		noise.timer--
+		return
+	}
+
+	noise.timer = noise.timerPeriod - 1
+	var feedback uint16
+	if noise.shortMode {
+		feedback = (noise.shift & 0x01) ^ ((noise.shift >> 6) & 0x01)
+	} else {
+		feedback = (noise.shift & 0x01) ^ ((noise.shift >> 1) & 0x01)
+	}
+	noise.shift >>= 1
+	noise.shift |= feedback << 14
+}
+
+func (noise *noise) clockLength() {
+	if !noise.lengthHalt && noise.length > 0 {
+		noise.length--
+	}
+}
+
+func (noise *noise) output() uint8 {
+	if noise.length == 0 || noise.shift&0x01 > 0 {
+		return 0
+	}
+	return noise.env.Output()
+}
+
+type dmc struct {
+	apu *apu
+
+	irqEnabled  bool
+	irq         bool
+	loop        bool
+	timer       uint16
+	timerPeriod uint16
+	level       uint8
+
+	sampleAddr   uint16
+	sampleLength uint16
+	currAddr     uint16
+	bytesLeft    uint16
+
+	buffer      uint8
+	bufferEmpty bool
+	fetching    bool
+	shift       uint8
+	bitsLeft    uint8
+	silence     bool
+}
+
+func (dmc *dmc) writeControl(data uint8) {
+	dmc.irqEnabled = data&0x80 > 0
+	dmc.loop = data&0x40 > 0
+	dmc.timerPeriod = dmcRateTable[data&0x0F]
+	if !dmc.irqEnabled {
+		dmc.irq = false
+	}
+}
+
+func (dmc *dmc) writeLevel(data uint8) {
+	dmc.level = data & 0x7F
+}
+
+func (dmc *dmc) writeAddress(data uint8) {
+	dmc.sampleAddr = dmcSampleBaseAddr + uint16(data)*64
+}
+
+func (dmc *dmc) writeLength(data uint8) {
+	dmc.sampleLength = uint16(data)*16 + 1
+}
+
+func (dmc *dmc) setEnabled(enabled bool) {
+	dmc.irq = false
+	if !enabled {
+		dmc.bytesLeft = 0
+	} else if dmc.bytesLeft == 0 {
+		dmc.restart()
+		dmc.fillBuffer()
+	}
+}
+
+func (dmc *dmc) restart() {
+	dmc.currAddr = dmc.sampleAddr
+	dmc.bytesLeft = dmc.sampleLength
+}
+
+// fillBuffer has the cpu fetch the next sample byte, it halts itself for a
+// few cycles to do it and hands the byte to loadBuffer
+func (dmc *dmc) fillBuffer() {
+	if !dmc.bufferEmpty || dmc.bytesLeft == 0 || dmc.fetching {
+		return
+	}
+	dmc.fetching = true
+	dmc.apu.sys.cpu.startDmcDma()
+}
+
+func (dmc *dmc) loadBuffer(data uint8) {
+	dmc.fetching = false
+	if dmc.bytesLeft == 0 {
+		return
+	}
+
+	dmc.buffer = data
+	dmc.bufferEmpty = false
+	if dmc.currAddr == 0xFFFF {
+		dmc.currAddr = 0x8000
+	} else {
+		dmc.currAddr++
+	}
+
+	dmc.bytesLeft--
+	if dmc.bytesLeft == 0 {
+		if dmc.loop {
+			dmc.restart()
+		} else if dmc.irqEnabled {
+			dmc.irq = true
+		}
+	}
+}
+
+func (dmc *dmc) clockTimer() {
+	if dmc.timer > 0 {
+		dmc.timer--
+		return
+	}
+	dmc.timer = dmc.timerPeriod - 1
+
+	if !dmc.silence {
+		if dmc.shift&0x01 > 0 {
+			if dmc.level <= 125 {
+				dmc.level += 2
+			}
+		} else if dmc.level >= 2 {
+			dmc.level -= 2
+		}
+	}
+	dmc.shift >>= 1
+
+	if dmc.bitsLeft > 0 {
+		dmc.bitsLeft--
+	}
+	if dmc.bitsLeft == 0 {
+		dmc.bitsLeft = 8
+		if dmc.bufferEmpty {
+			dmc.silence = true
+		} else {
+			dmc.silence = false
+			dmc.shift = dmc.buffer
+			dmc.bufferEmpty = true
+			dmc.fillBuffer()
+		}
+	}
+}
+
+func (dmc *dmc) output() uint8 {
+	return dmc.level
+}
+
+type apu struct {
+	sys      *System
+	pulse1   pulse
+	pulse2   pulse
+	triangle triangle
+	noise    noise
+	dmc      dmc
+
+	cycle           int
+	fiveStepMode    bool
+	frameIrqInhibit bool
+	frameIrq        bool
+	sample          float32
+
+	levels [AudioChannelCount]float32
+	muted  [AudioChannelCount]bool
+	soloed [AudioChannelCount]bool
+}
+
+func NewApu(sys *System) *apu {
+	apu := &apu{
+		sys: sys,
+	}
+	apu.pulse1.onesCompl = true
+	apu.noise.shift = 1
+	apu.noise.timerPeriod = noisePeriodTable[0]
+	apu.dmc.apu = apu
+	apu.dmc.timerPeriod = dmcRateTable[0]
+	apu.dmc.bufferEmpty = true
+	apu.dmc.bitsLeft = 8
+	apu.dmc.silence = true
+	return apu
+}
+
+func (apu *apu) Clock() {
+	// the triangle, noise and dmc timers run at cpu speed while the pulse
+	// timers are only clocked every other cpu cycle
+	apu.triangle.clockTimer()
+	apu.noise.clockTimer()
+	apu.dmc.clockTimer()
+	if apu.cycle%2 == 1 {
+		apu.pulse1.ClockTimer()
+		apu.pulse2.ClockTimer()
+	}
+
+	apu.clockFrameCounter()
+
+	if apu.irq() {
+		apu.sys.cpu.Irq()
+	}
+
+	apu.updateLevels()
+	apu.sample = apu.mix(apu.audibleChannels())
+}
+
+func (apu *apu) clockFrameCounter() {
+	apu.cycle++
+	switch apu.cycle {
+	case frameStep1, frameStep3:
+		apu.clockQuarterFrame()
+	case frameStep2:
+		apu.clockQuarterFrame()
+		apu.clockHalfFrame()
+	case frameStep4 - 1:
+		if !apu.fiveStepMode {
+			apu.setFrameIrq()
+		}
+	case frameStep4:
+		if !apu.fiveStepMode {
+			apu.clockQuarterFrame()
+			apu.clockHalfFrame()
+			apu.setFrameIrq()
+		}
+	case fourStepPeriod:
+		if !apu.fiveStepMode {
+			apu.setFrameIrq()
+			apu.cycle = 0
+		}
+	case frameStep5:
+		apu.clockQuarterFrame()
+		apu.clockHalfFrame()
+	case fiveStepPeriod:
+		apu.cycle = 0
+	}
+}
+
+func (apu *apu) setFrameIrq() {
+	if !apu.frameIrqInhibit {
+		apu.frameIrq = true
+	}
+}
+
+func (apu *apu) clockQuarterFrame() {
+	apu.pulse1.ClockEnvelope()
+	apu.pulse2.ClockEnvelope()
+	apu.noise.env.Clock()
+	apu.triangle.clockLinear()
+}
+
+func (apu *apu) clockHalfFrame() {
+	apu.pulse1.ClockLength()
+	apu.pulse1.clockSweep()
+	apu.pulse2.ClockLength()
+	apu.pulse2.clockSweep()
+	apu.triangle.clockLength()
+	apu.noise.clockLength()
+}
+
+func (apu *apu) irq() bool {
+	return apu.frameIrq || apu.dmc.irq
+}
+
+func (apu *apu) updateLevels() {
+	apu.levels[ChannelPulse1] = float32(apu.pulse1.output())
+	apu.levels[ChannelPulse2] = float32(apu.pulse2.output())
+	apu.levels[ChannelTriangle] = float32(apu.triangle.output())
+	apu.levels[ChannelNoise] = float32(apu.noise.output())
+	apu.levels[ChannelDmc] = float32(apu.dmc.output())
+	if apu.sys.cartridge != nil {
+		apu.levels[ChannelExpansion] = apu.sys.cartridge.AudioSample()
+	}
+}
+
+// audibleChannels returns a bit mask of the channels that make it into the
+// mix. soloing any channel silences every channel that isn't soloed
+func (apu *apu) audibleChannels() uint {
+	var mask uint
+	var soloMask uint
+	for channel := range AudioChannelCount {
+		if !apu.muted[channel] {
+			mask |= 1 << channel
+		}
+		if apu.soloed[channel] {
+			soloMask |= 1 << channel
+		}
+	}
+	if soloMask != 0 {
+		return soloMask
+	}
+	return mask
+}
+
+// channelSample returns what the mixer would output if the given channel was
+// the only one playing
+func (apu *apu) channelSample(channel AudioChannel) float32 {
+	return apu.mix(1 << channel)
+}
+
+func (apu *apu) mix(channels uint) float32 {
+	var levels [AudioChannelCount]float32
+	for channel := range AudioChannelCount {
+		if channels&(1<<channel) > 0 {
+			levels[channel] = apu.levels[channel]
+		}
+	}
+
+	pulseOut := sound.MixPulses(levels[ChannelPulse1] + levels[ChannelPulse2])
+
+	var tndOut float32
+	tndSum := levels[ChannelTriangle]/8227 +
+		levels[ChannelNoise]/12241 +
+		levels[ChannelDmc]/22638
+	if tndSum > 0 {
+		tndOut = 159.79 / (1/tndSum + 100)
+	}
+
+	return pulseOut + tndOut + levels[ChannelExpansion]
+}
+
+func (apu *apu) readStatus() uint8 {
+	var status uint8
+
+	if apu.pulse1.Length > 0 {
+		status |= pulse1StatusBitMask
+	}
+
+	if apu.pulse2.Length > 0 {
+		status |= pulse2StatusBitMask
+	}
+
+	if apu.triangle.length > 0 {
+		status |= triangleStatusBitMask
+	}
+
+	if apu.noise.length > 0 {
+		status |= noiseStatusBitMask
+	}
+
+	if apu.dmc.bytesLeft > 0 {
+		status |= dmcStatusBitMask
+	}
+
+	if apu.frameIrq {
+		status |= frameIrqStatusBitMask
+	}
+
+	if apu.dmc.irq {
+		status |= dmcIrqStatusBitMask
+	}
+
+	apu.frameIrq = false
+	return status
+}
+
+func (apu *apu) writeStatus(data uint8) {
+	apu.pulse1.SetEnabled(data&pulse1StatusBitMask > 0)
+	apu.pulse2.SetEnabled(data&pulse2StatusBitMask > 0)
+	apu.triangle.setEnabled(data&triangleStatusBitMask > 0)
+	apu.noise.setEnabled(data&noiseStatusBitMask > 0)
+	apu.dmc.setEnabled(data&dmcStatusBitMask > 0)
+}
+
+func (apu *apu) writeFrameCounter(data uint8) {
+	apu.fiveStepMode = data&frameModeBitMask > 0
+	apu.frameIrqInhibit = data&frameIrqInhibitBitMask > 0
+	if apu.frameIrqInhibit {
+		apu.frameIrq = false
+	}
+
+	apu.cycle = 0
+	if apu.fiveStepMode {
+		apu.clockQuarterFrame()
+		apu.clockHalfFrame()
+	}
+}
+
+func (apu *apu) writeChannel(addr uint16, data uint8) {
+	switch addr {
+	case 0x4000:
+		apu.pulse1.WriteControl(data)
+	case 0x4001:
+		apu.pulse1.writeSweep(data)
+	case 0x4002:
+		apu.pulse1.WriteTimerLow(data)
+	case 0x4003:
+		apu.pulse1.WriteTimerHigh(data)
+	case 0x4004:
+		apu.pulse2.WriteControl(data)
+	case 0x4005:
+		apu.pulse2.writeSweep(data)
+	case 0x4006:
+		apu.pulse2.WriteTimerLow(data)
+	case 0x4007:
+		apu.pulse2.WriteTimerHigh(data)
+	case 0x4008:
+		apu.triangle.writeControl(data)
+	case 0x400A:
+		apu.triangle.writeTimerLow(data)
+	case 0x400B:
+		apu.triangle.writeTimerHigh(data)
+	case 0x400C:
+		apu.noise.writeControl(data)
+	case 0x400E:
+		apu.noise.writePeriod(data)
+	case 0x400F:
+		apu.noise.writeLength(data)
+	case 0x4010:
+		apu.dmc.writeControl(data)
+	case 0x4011:
+		apu.dmc.writeLevel(data)
+	case 0x4012:
+		apu.dmc.writeAddress(data)
+	case 0x4013:
+		apu.dmc.writeLength(data)
+	}
+}
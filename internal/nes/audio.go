@@ -0,0 +1,103 @@
+package nes
+
+import "math"
+
+const cpuClockRate float64 = 1789773
+
+type AudioSink interface {
+	SampleRate() int
+	WriteSamples(samples []float32)
+}
+
+type filter struct {
+	highPass bool
+	alpha    float64
+	prevIn   float64
+	prevOut  float64
+}
+
+func newHighPassFilter(sampleRate int, cutoff float64) filter {
+	rc := 1 / (2 * math.Pi * cutoff)
+	dt := 1 / float64(sampleRate)
+	return filter{highPass: true, alpha: rc / (rc + dt)}
+}
+
+func newLowPassFilter(sampleRate int, cutoff float64) filter {
+	rc := 1 / (2 * math.Pi * cutoff)
+	dt := 1 / float64(sampleRate)
+	return filter{alpha: dt / (rc + dt)}
+}
+
+func (filter *filter) apply(sample float64) float64 {
+	if filter.highPass {
+		filter.prevOut = filter.alpha * (filter.prevOut + sample - filter.prevIn)
+	} else {
+		filter.prevOut += filter.alpha * (sample - filter.prevOut)
+	}
+	filter.prevIn = sample
+	return filter.prevOut
+}
+
+// resampler converts the per cpu cycle apu output to the sink's sample rate.
+// each output sample is the average of all cpu cycles it covers, which
+// band-limits the signal before decimating it, and then runs through the same
+// filter chain the nes has between the apu and the audio out jack
+type resampler struct {
+	sampleRate      int
+	cyclesPerSample float64
+	cycles          float64
+	sum             float64
+	count           int
+	filters         []filter
+	samples         []float32
+}
+
+func newResampler(sampleRate int) *resampler {
+	return &resampler{
+		sampleRate:      sampleRate,
+		cyclesPerSample: cpuClockRate / float64(sampleRate),
+		filters: []filter{
+			newHighPassFilter(sampleRate, 90),
+			newHighPassFilter(sampleRate, 440),
+			newLowPassFilter(sampleRate, 14000),
+		},
+	}
+}
+
+// setRateAdjust speeds up or slows down how fast samples are produced by the
+// given factor without touching the filters, for keeping the sink's buffer
+// from drifting
+func (resampler *resampler) setRateAdjust(factor float64) {
+	resampler.cyclesPerSample = cpuClockRate / (float64(resampler.sampleRate) * factor)
+}
+
+func (resampler *resampler) push(sample float32) {
+	resampler.sum += float64(sample)
+	resampler.count++
+	resampler.cycles++
+	if resampler.cycles < resampler.cyclesPerSample {
+		return
+	}
+	resampler.cycles -= resampler.cyclesPerSample
+
+	output := resampler.sum / float64(resampler.count)
+	resampler.sum = 0
+	resampler.count = 0
+	for i := range resampler.filters {
+		output = resampler.filters[i].apply(output)
+	}
+	resampler.samples = append(resampler.samples, float32(output))
+}
+
+func (resampler *resampler) flush(sink AudioSink) {
+	if len(resampler.samples) == 0 {
+		return
+	}
+	sink.WriteSamples(resampler.samples)
+	resampler.samples = resampler.samples[:0]
+}
+
+type channelTap struct {
+	sink      AudioSink
+	resampler *resampler
+}
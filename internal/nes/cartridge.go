@@ -9,15 +9,29 @@ import (
 	"github.com/theaaronruss/nes-emulator/internal/mapper"
 )
 
+const (
+	programRomChunkSize   int = 16384
+	characterRomChunkSize int = 8192
+	programRamChunkSize   int = 8192
+	characterRamSize      int = 8192
+	fourScreenRamSize     int = 2048
+)
+
 type Cartridge struct {
-	programData   []uint8
-	characterData []uint8
+	board  mapper.Board
+	header Header
 
-	// mapperId            int
 	mapper              mapper.Mapper
-	programDataChunks   int
-	characterDataChunks int
-	horizontalMirror    bool
+	cpuClocker          mapper.CpuClocker
+	ppuBusWatcher       mapper.PpuBusWatcher
+	nameTableMapper     mapper.NameTableMapper
+	characterVramMapper mapper.CharacterVramMapper
+	cpuWriteWatcher     mapper.CpuWriteWatcher
+	audioSource         mapper.AudioSource
+	fds                 *mapper.Fds
+	nameTableRam        []uint8
+	savePath            string
+	savedData           []uint8
 }
 
 func NewCartridge(filePath string) (*Cartridge, error) {
@@ -28,7 +42,7 @@ func NewCartridge(filePath string) (*Cartridge, error) {
 		return nil, fmt.Errorf(errorMessage, err)
 	}
 	defer file.Close()
-	cartridge := &Cartridge{}
+	cartridge := &Cartridge{savePath: savePath(filePath)}
 
 	err = cartridge.parseHeader(file)
 	if err != nil {
@@ -45,58 +59,94 @@ func NewCartridge(filePath string) (*Cartridge, error) {
 		return nil, fmt.Errorf(errorMessage, err)
 	}
 
+	cartridge.createMapper()
+	err = cartridge.loadSave()
+	if err != nil {
+		return nil, err
+	}
 	return cartridge, nil
 }
 
 func (cartridge *Cartridge) parseHeader(file *os.File) error {
-	header := make([]byte, 16)
-	n, err := file.Read(header)
-	if n != len(header) {
+	data := make([]byte, headerSize)
+	n, err := file.Read(data)
+	if n != len(data) {
 		return errors.New("unexpected end of file")
 	}
 	if err != nil {
 		return err
 	}
 
-	var mapperId = int(header[7] & 0xF0)
-	mapperId |= int(header[6]) >> 4
-	createMapperFunc, ok := mapper.Mappers[mapperId]
-	if !ok {
+	cartridge.header, err = parseHeader(data)
+	if err != nil {
+		return err
+	}
+	if _, ok := mapper.Mappers[cartridge.header.Mapper]; !ok {
 		return errors.New("mapper required by cartridge not implemented yet")
 	}
-	cartridge.mapper = createMapperFunc()
+	cartridge.board.Mirroring = cartridge.header.Mirroring
+	cartridge.board.Submapper = cartridge.header.Submapper
+	cartridge.board.ProgramRamSized = cartridge.header.Nes20
 
-	cartridge.programDataChunks = int(header[4])
-	cartridge.characterDataChunks = int(header[5])
-	cartridge.horizontalMirror = header[6]&0x01 > 0
+	// check the sizes against the file before any memory is allocated for
+	// them, a corrupt header can ask for far more than there is
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	romSize := headerSize + cartridge.header.ProgramRomSize + cartridge.header.CharacterRomSize
+	if cartridge.header.Trainer {
+		romSize += trainerSize
+	}
+	if int64(romSize) > info.Size() {
+		return errors.New("rom is smaller than its header says")
+	}
+
+	// four screen boards have their own vram for the name tables that the
+	// console's 2k doesn't cover
+	if cartridge.header.FourScreen {
+		cartridge.nameTableRam = make([]uint8, fourScreenRamSize)
+	}
 
 	// skip trainer section if present
-	if header[6]&0x04 > 0 {
-		file.Seek(512, io.SeekCurrent)
+	if cartridge.header.Trainer {
+		file.Seek(int64(trainerSize), io.SeekCurrent)
 	}
 
 	return nil
 }
 
 func (cartridge *Cartridge) parseProgramData(file *os.File) error {
-	cartridge.programData = make([]uint8, 16384*cartridge.programDataChunks)
-	n, err := file.Read(cartridge.programData)
-	if n != len(cartridge.programData) {
+	cartridge.board.ProgramRom = make([]uint8, cartridge.header.ProgramRomSize)
+	n, err := file.Read(cartridge.board.ProgramRom)
+	if n != len(cartridge.board.ProgramRom) {
 		return errors.New("unexpected end of file")
 	}
 	if err != nil {
 		return err
 	}
+
+	programRamSize := cartridge.header.ProgramRamSize + cartridge.header.ProgramNvramSize
+	cartridge.board.ProgramRam = make([]uint8, programRamSize)
 	return nil
 }
 
 func (cartridge *Cartridge) parseCharacterData(file *os.File) error {
-	cartridge.characterData = make([]uint8, 8192*cartridge.characterDataChunks)
-	if cartridge.characterDataChunks < 1 {
+	// cartridges without character rom have character ram instead. nes 2.0
+	// headers say how much, otherwise it's the usual 8k
+	if cartridge.header.CharacterRomSize == 0 {
+		size := cartridge.header.CharacterRamSize + cartridge.header.CharacterNvramSize
+		if size == 0 {
+			size = characterRamSize
+		}
+		cartridge.board.CharacterData = make([]uint8, size)
+		cartridge.board.CharacterRam = true
 		return nil
 	}
-	n, err := file.Read(cartridge.characterData)
-	if n != len(cartridge.characterData) {
+
+	cartridge.board.CharacterData = make([]uint8, cartridge.header.CharacterRomSize)
+	n, err := file.Read(cartridge.board.CharacterData)
+	if n != len(cartridge.board.CharacterData) {
 		return errors.New("unexpected end of file")
 	}
 	if err != nil {
@@ -105,16 +155,99 @@ func (cartridge *Cartridge) parseCharacterData(file *os.File) error {
 	return nil
 }
 
-func (cartridge *Cartridge) ReadProgramData(addr uint16) uint8 {
-	mappedAddr := cartridge.mapper.TranslateProgramDataAddress(cartridge.programDataChunks, addr)
-	return cartridge.programData[mappedAddr]
+func (cartridge *Cartridge) createMapper() {
+	cartridge.useMapper(mapper.Mappers[cartridge.header.Mapper](&cartridge.board))
+}
+
+// Header returns what the rom's header says about the cartridge. disk system
+// images have no header
+func (cartridge *Cartridge) Header() Header {
+	return cartridge.header
+}
+
+func (cartridge *Cartridge) useMapper(m mapper.Mapper) {
+	cartridge.mapper = m
+	cartridge.cpuClocker, _ = cartridge.mapper.(mapper.CpuClocker)
+	cartridge.ppuBusWatcher, _ = cartridge.mapper.(mapper.PpuBusWatcher)
+	cartridge.nameTableMapper, _ = cartridge.mapper.(mapper.NameTableMapper)
+	cartridge.characterVramMapper, _ = cartridge.mapper.(mapper.CharacterVramMapper)
+	cartridge.cpuWriteWatcher, _ = cartridge.mapper.(mapper.CpuWriteWatcher)
+	cartridge.audioSource, _ = cartridge.mapper.(mapper.AudioSource)
+}
+
+func (cartridge *Cartridge) ReadProgramData(addr uint16) (uint8, bool) {
+	return cartridge.mapper.ReadCpu(addr)
 }
 
-func (cartridge *Cartridge) ReadCharacterData(addr uint16) uint8 {
-	mappedAddr := cartridge.mapper.TranslateCharacterDataAddress(cartridge.characterDataChunks, addr)
-	return cartridge.characterData[mappedAddr]
+func (cartridge *Cartridge) WriteProgramData(addr uint16, data uint8) {
+	cartridge.mapper.WriteCpu(addr, data)
+}
+
+func (cartridge *Cartridge) ReadCharacterData(addr uint16, vram []uint8) uint8 {
+	if cartridge.characterVramMapper != nil {
+		return cartridge.characterVramMapper.ReadCharacterVram(addr, vram)
+	}
+	return cartridge.mapper.ReadPpu(addr)
 }
 
-func (cartridge *Cartridge) HasHorizontalNameTableMirroring() bool {
-	return cartridge.horizontalMirror
+func (cartridge *Cartridge) WriteCharacterData(addr uint16, data uint8, vram []uint8) {
+	if cartridge.characterVramMapper != nil {
+		cartridge.characterVramMapper.WriteCharacterVram(addr, data, vram)
+		return
+	}
+	cartridge.mapper.WritePpu(addr, data)
+}
+
+// NameTableMirroring returns how the name tables are laid out. four screen
+// boards ignore the mapper's mirroring since they don't need any
+func (cartridge *Cartridge) NameTableMirroring() mapper.Mirroring {
+	switch {
+	case cartridge.nameTableMapper != nil:
+		return mapper.MirrorMapperControlled
+	case cartridge.header.FourScreen:
+		return mapper.MirrorFourScreen
+	}
+	return cartridge.mapper.Mirroring()
+}
+
+// NameTableRam returns the cartridge's extra vram on four screen boards
+func (cartridge *Cartridge) NameTableRam() []uint8 {
+	return cartridge.nameTableRam
+}
+
+func (cartridge *Cartridge) Irq() bool {
+	return cartridge.mapper.Irq()
+}
+
+func (cartridge *Cartridge) ClockCpu() {
+	if cartridge.cpuClocker != nil {
+		cartridge.cpuClocker.ClockCpu()
+	}
+}
+
+func (cartridge *Cartridge) WatchPpuAddr(addr uint16) {
+	if cartridge.ppuBusWatcher != nil {
+		cartridge.ppuBusWatcher.WatchPpuAddr(addr)
+	}
+}
+
+func (cartridge *Cartridge) ReadNameTable(addr uint16, vram []uint8) uint8 {
+	return cartridge.nameTableMapper.ReadNameTable(addr, vram)
+}
+
+func (cartridge *Cartridge) WriteNameTable(addr uint16, data uint8, vram []uint8) {
+	cartridge.nameTableMapper.WriteNameTable(addr, data, vram)
+}
+
+func (cartridge *Cartridge) WatchCpuWrite(addr uint16, data uint8) {
+	if cartridge.cpuWriteWatcher != nil {
+		cartridge.cpuWriteWatcher.WatchCpuWrite(addr, data)
+	}
+}
+
+func (cartridge *Cartridge) AudioSample() float32 {
+	if cartridge.audioSource == nil {
+		return 0
+	}
+	return cartridge.audioSource.AudioSample()
 }
@@ -24,8 +24,20 @@ const (
 	stackBase           uint16 = 0x0100
 	initialStackPointer uint8  = 0xFD
 	initialStatus       uint8  = 0x24
+	resetCycles         int    = 7
+
+	// ane and lxa or a with a value that depends on the chip and how warm it
+	// is, this is the one most consoles give
+	unstableMagic uint8 = 0xEE
 )
 
+// interruptInstruction is run in place of an opcode to handle an nmi or irq
+var interruptInstruction = instruction{"INT", addrModeImplied, 0, accessCustom, (*cpu).interrupt}
+
+// the cpu reads or writes the bus on every cycle, so each instruction is run
+// a cycle at a time. cycle 1 fetches the opcode, the cycles after it work
+// out the address from the addressing mode and then the access kind of the
+// instruction decides what happens at that address
 type cpu struct {
 	a      uint8
 	x      uint8
@@ -35,10 +47,42 @@ type cpu struct {
 	status uint8
 
 	sys         *System
-	cycleDelay  int
+	stallCycles int
 	totalCycles int
 	handleIrq   bool
 	handleNmi   bool
+
+	// halted is set by the jam opcodes, which lock up the cpu until the
+	// console is reset
+	halted bool
+
+	// the interrupt lines are sampled at the end of every cycle, and what
+	// they were at the end of an instruction's second to last cycle decides
+	// whether an interrupt is handled before the next one
+	interruptPoll    bool
+	interruptPending bool
+	skipPoll         bool
+
+	// dma takes the bus from the cpu to copy sprites to oam and to fetch dmc
+	// samples, see clockDma
+	dmaHalted    bool
+	oamDma       bool
+	oamDmaAddr   uint16
+	oamDmaValue  uint8
+	oamDmaLoaded bool
+	dmcDma       bool
+	dmcDmaDelay  bool
+
+	// the instruction being run. cycle is 0 between instructions
+	instr       *instruction
+	cycle       int
+	dataCycle   int
+	addrReady   bool
+	addr        uint16
+	pointer     uint8
+	value       uint8
+	pageCrossed bool
+	vector      uint16
 }
 
 func NewCpu(sys *System) *cpu {
@@ -46,53 +90,264 @@ func NewCpu(sys *System) *cpu {
 	pcHigh := sys.read(resetVector + 1)
 
 	return &cpu{
-		sp:         initialStackPointer,
-		pc:         uint16(pcHigh)<<8 | uint16(pcLow),
-		status:     initialStatus,
-		sys:        sys,
-		cycleDelay: 7,
+		sp:          initialStackPointer,
+		pc:          uint16(pcHigh)<<8 | uint16(pcLow),
+		status:      initialStatus,
+		sys:         sys,
+		stallCycles: resetCycles,
 	}
 }
 
 func (cpu *cpu) Clock() {
-	if cpu.cycleDelay <= 0 {
+	switch {
+	case cpu.halted:
+	case cpu.oamDma || cpu.dmcDma:
+		cpu.clockDma()
+	case cpu.stallCycles > 0:
+		cpu.stallCycles--
+	case cpu.cycle == 0:
+		cpu.startInstruction()
+	default:
+		cpu.cycle++
+		cpu.continueInstruction()
+	}
+	cpu.pollInterrupts()
+	cpu.totalCycles++
+
+	// the irq line is level triggered so it has to be asserted again by its
+	// source every cycle it should stay active
+	cpu.handleIrq = false
+}
+
+// startInstruction fetches the next opcode, or starts handling an interrupt
+// instead
+func (cpu *cpu) startInstruction() {
+	cpu.cycle = 1
+	cpu.dataCycle = 0
+	cpu.addrReady = false
+	cpu.pageCrossed = false
+
+	if cpu.interruptPending {
+		cpu.interruptPending = false
 		if cpu.handleNmi {
-			oldAddrLow := uint8(cpu.pc)
-			oldAddrHigh := uint8(cpu.pc & 0xFF00 >> 8)
-			cpu.stackPush(oldAddrHigh)
-			cpu.stackPush(oldAddrLow)
-			cpu.stackPush(cpu.status & ^breakFlagMask | unusedFlagMask)
-			cpu.updateFlag(intDisableFlagMask, true)
-			low := cpu.sys.read(nmiVector)
-			high := cpu.sys.read(nmiVector + 1)
-			address := uint16(high)<<8 | uint16(low)
-			cpu.pc = address
 			cpu.handleNmi = false
-			cpu.cycleDelay += 7
-		} else if cpu.handleIrq && cpu.status&intDisableFlagMask == 0 {
-			oldAddrLow := uint8(cpu.pc)
-			oldAddrHigh := uint8(cpu.pc & 0xFF00 >> 8)
-			cpu.stackPush(oldAddrHigh)
-			cpu.stackPush(oldAddrLow)
-			cpu.stackPush(cpu.status & ^breakFlagMask | unusedFlagMask)
-			cpu.updateFlag(intDisableFlagMask, true)
-			newAddrLow := cpu.sys.read(irqVector)
-			newAddrHigh := cpu.sys.read(irqVector + 1)
-			address := uint16(newAddrHigh)<<8 | uint16(newAddrLow)
-			cpu.pc = address
-			cpu.handleIrq = false
-			cpu.cycleDelay += 7
+			cpu.startInterrupt(nmiVector)
+		} else {
+			cpu.startInterrupt(irqVector)
 		}
+		return
+	}
 
-		opcode := cpu.sys.read(cpu.pc)
-		instruction := opcodes[opcode]
-		currPc := cpu.pc
-		cpu.pc += uint16(instruction.bytes)
-		instruction.fn(cpu, instruction.addrMode, currPc)
-		cpu.cycleDelay += instruction.cycles
+	cpu.instr = &opcodes[cpu.fetch()]
+	switch cpu.instr.addrMode {
+	case addrModeImmediate:
+		cpu.addr = cpu.pc
+		cpu.pc++
+		cpu.addrReady = true
+	case addrModeImplied, addrModeAccumulator:
+		cpu.addrReady = true
 	}
-	cpu.cycleDelay--
-	cpu.totalCycles++
+}
+
+func (cpu *cpu) startInterrupt(vector uint16) {
+	cpu.instr = &interruptInstruction
+	cpu.vector = vector
+	cpu.sys.read(cpu.pc)
+}
+
+func (cpu *cpu) continueInstruction() {
+	instr := cpu.instr
+	if instr.access == accessCustom {
+		instr.fn(cpu)
+		return
+	}
+	if !cpu.addrReady {
+		cpu.addrReady = cpu.addressCycle()
+		return
+	}
+
+	switch instr.access {
+	case accessNone:
+		// instructions without an operand still read the byte after the
+		// opcode
+		cpu.sys.read(cpu.pc)
+		instr.fn(cpu)
+		cpu.finish()
+	case accessRead:
+		cpu.readCycle()
+	case accessWrite:
+		cpu.writeCycle()
+	case accessModify:
+		cpu.modifyCycle()
+	}
+	cpu.dataCycle++
+}
+
+func (cpu *cpu) finish() {
+	cpu.cycle = 0
+	cpu.interruptPending = cpu.interruptPoll
+}
+
+func (cpu *cpu) pollInterrupts() {
+	if cpu.skipPoll {
+		cpu.skipPoll = false
+		return
+	}
+	cpu.interruptPoll = cpu.handleNmi || cpu.handleIrq && cpu.status&intDisableFlagMask == 0
+}
+
+// addressCycle runs a cycle of working out the address for the addressing
+// mode, it returns true once the address is ready
+func (cpu *cpu) addressCycle() bool {
+	switch cpu.instr.addrMode {
+	case addrModeZeroPage:
+		cpu.addr = uint16(cpu.fetch())
+		return true
+	case addrModeZeroPageX, addrModeZeroPageY:
+		if cpu.cycle == 2 {
+			cpu.addr = uint16(cpu.fetch())
+			return false
+		}
+		// the base address is read while the index is added
+		cpu.sys.read(cpu.addr)
+		cpu.addr = uint16(uint8(cpu.addr) + cpu.indexRegister())
+		return true
+	case addrModeAbsolute:
+		if cpu.cycle == 2 {
+			cpu.addr = uint16(cpu.fetch())
+			return false
+		}
+		cpu.addr |= uint16(cpu.fetch()) << 8
+		return true
+	case addrModeAbsoluteX, addrModeAbsoluteY:
+		if cpu.cycle == 2 {
+			cpu.addr = uint16(cpu.fetch())
+			return false
+		}
+		cpu.addIndex(cpu.addr|uint16(cpu.fetch())<<8, cpu.indexRegister())
+		return true
+	case addrModeIndexedIndir:
+		switch cpu.cycle {
+		case 2:
+			cpu.pointer = cpu.fetch()
+		case 3:
+			cpu.sys.read(uint16(cpu.pointer))
+			cpu.pointer += cpu.x
+		case 4:
+			cpu.addr = uint16(cpu.sys.read(uint16(cpu.pointer)))
+		case 5:
+			cpu.addr |= uint16(cpu.sys.read(uint16(cpu.pointer+1))) << 8
+			return true
+		}
+		return false
+	case addrModeIndirIndexed:
+		switch cpu.cycle {
+		case 2:
+			cpu.pointer = cpu.fetch()
+		case 3:
+			cpu.addr = uint16(cpu.sys.read(uint16(cpu.pointer)))
+		case 4:
+			base := cpu.addr | uint16(cpu.sys.read(uint16(cpu.pointer+1)))<<8
+			cpu.addIndex(base, cpu.y)
+			return true
+		}
+		return false
+	}
+	panic("invalid address mode")
+}
+
+func (cpu *cpu) indexRegister() uint8 {
+	switch cpu.instr.addrMode {
+	case addrModeZeroPageX, addrModeAbsoluteX:
+		return cpu.x
+	}
+	return cpu.y
+}
+
+func (cpu *cpu) addIndex(base uint16, index uint8) {
+	cpu.addr = base + uint16(index)
+	cpu.pageCrossed = base&0xFF00 != cpu.addr&0xFF00
+}
+
+// indexed modes only add the index to the low byte of the address at first
+// and fix the high byte on the next cycle
+func (cpu *cpu) fixedUpLate() bool {
+	switch cpu.instr.addrMode {
+	case addrModeAbsoluteX, addrModeAbsoluteY, addrModeIndirIndexed:
+		return true
+	}
+	return false
+}
+
+func (cpu *cpu) unfixedAddr() uint16 {
+	if cpu.pageCrossed {
+		return cpu.addr - 0x0100
+	}
+	return cpu.addr
+}
+
+// readCycle reads the operand and runs the instruction on it. reads from
+// the address before its high byte is fixed are only wasted when indexing
+// crossed a page
+func (cpu *cpu) readCycle() {
+	if cpu.dataCycle == 0 && cpu.pageCrossed {
+		cpu.sys.read(cpu.unfixedAddr())
+		return
+	}
+	cpu.value = cpu.sys.read(cpu.addr)
+	cpu.instr.fn(cpu)
+	cpu.finish()
+}
+
+// writeCycle writes what the instruction stores. indexed writes always read
+// the address before its high byte is fixed, since they can't take back a
+// write to the wrong address
+func (cpu *cpu) writeCycle() {
+	if cpu.dataCycle == 0 && cpu.fixedUpLate() {
+		cpu.sys.read(cpu.unfixedAddr())
+		return
+	}
+	cpu.instr.fn(cpu)
+	cpu.sys.write(cpu.addr, cpu.value)
+	cpu.finish()
+}
+
+// modifyCycle reads the operand, writes it straight back while the
+// instruction works out the new value and then writes the new value
+func (cpu *cpu) modifyCycle() {
+	if cpu.instr.addrMode == addrModeAccumulator {
+		cpu.sys.read(cpu.pc)
+		cpu.value = cpu.a
+		cpu.instr.fn(cpu)
+		cpu.a = cpu.value
+		cpu.finish()
+		return
+	}
+
+	step := cpu.dataCycle
+	if cpu.fixedUpLate() {
+		if step == 0 {
+			cpu.sys.read(cpu.unfixedAddr())
+			return
+		}
+		step--
+	}
+	switch step {
+	case 0:
+		cpu.value = cpu.sys.read(cpu.addr)
+	case 1:
+		cpu.sys.write(cpu.addr, cpu.value)
+		cpu.instr.fn(cpu)
+	case 2:
+		cpu.sys.write(cpu.addr, cpu.value)
+		cpu.finish()
+	}
+}
+
+func (cpu *cpu) fetch() uint8 {
+	data := cpu.sys.read(cpu.pc)
+	cpu.pc++
+	return data
 }
 
 func (cpu *cpu) logInstruction(pc uint16, instr *instruction) {
@@ -113,6 +368,69 @@ func (cpu *cpu) Nmi() {
 	cpu.handleNmi = true
 }
 
+func (cpu *cpu) atInstructionBoundary() bool {
+	return cpu.cycle == 0 && cpu.stallCycles == 0 && !cpu.oamDma && !cpu.dmcDma
+}
+
+// startOamDma copies the 256 bytes of the given page to oam through $2004,
+// starting on the next cycle
+func (cpu *cpu) startOamDma(page uint8) {
+	cpu.oamDma = true
+	cpu.oamDmaAddr = uint16(page) << 8
+	cpu.oamDmaLoaded = false
+}
+
+// startDmcDma fetches the dmc's next sample byte, starting on the next cycle
+func (cpu *cpu) startDmcDma() {
+	cpu.dmcDma = true
+	cpu.dmcDmaDelay = true
+}
+
+// clockDma runs a cycle of dma instead of the cpu. the first cycle halts the
+// cpu, and the dmc waits another cycle after that. dma reads on even cycles
+// and writes on odd ones, so cycles that don't line up with that are spent
+// waiting. the dmc gets the bus first when both want to read
+func (cpu *cpu) clockDma() {
+	read := cpu.totalCycles%2 == 0
+	switch {
+	case !cpu.dmaHalted:
+		cpu.dmaHalted = true
+	case cpu.dmcDmaDelay:
+		cpu.dmcDmaDelay = false
+	case cpu.dmcDma && read:
+		dmc := &cpu.sys.apu.dmc
+		dmc.loadBuffer(cpu.sys.read(dmc.currAddr))
+		cpu.dmcDma = false
+	case cpu.oamDma && read && !cpu.oamDmaLoaded:
+		cpu.oamDmaValue = cpu.sys.read(cpu.oamDmaAddr)
+		cpu.oamDmaLoaded = true
+	case cpu.oamDma && !read && cpu.oamDmaLoaded:
+		cpu.sys.write(oamData, cpu.oamDmaValue)
+		cpu.oamDmaLoaded = false
+		cpu.oamDmaAddr++
+		cpu.oamDma = cpu.oamDmaAddr&0x00FF != 0
+	}
+
+	if !cpu.oamDma && !cpu.dmcDma {
+		cpu.dmaHalted = false
+	}
+}
+
+// abortInstruction drops the instruction that is part way through, for when
+// the registers are changed from outside of the running program
+func (cpu *cpu) abortInstruction() {
+	cpu.cycle = 0
+}
+
+// call jumps to a subroutine from outside of the running program, the rts at
+// the end of the subroutine returns to returnAddr
+func (cpu *cpu) call(addr uint16, returnAddr uint16) {
+	returnAddr--
+	cpu.stackPush(uint8(returnAddr >> 8))
+	cpu.stackPush(uint8(returnAddr))
+	cpu.pc = addr
+}
+
 func (cpu *cpu) updateFlag(flag uint8, value bool) {
 	if value {
 		cpu.status |= flag
@@ -137,125 +455,77 @@ func (cpu *cpu) stackPop() uint8 {
 	return cpu.sys.read(address)
 }
 
-func (cpu *cpu) mustGetAddress(addrMode addressMode, pc uint16) (uint16, bool) {
-	switch addrMode {
-	case addrModeImplied:
-		return pc, false
-	case addrModeImmediate:
-		return pc, false
-	case addrModeZeroPage:
-		return cpu.getZeroPageAddress(pc), false
-	case addrModeZeroPageX:
-		return cpu.getZeroPageOffsetAddress(pc, cpu.x), false
-	case addrModeZeroPageY:
-		return cpu.getZeroPageOffsetAddress(pc, cpu.y), false
-	case addrModeAbsolute:
-		return cpu.getAbsoluteAddress(pc), false
-	case addrModeAbsoluteX:
-		return cpu.getAbsoluteOffsetAddress(pc, cpu.x)
-	case addrModeAbsoluteY:
-		return cpu.getAbsoluteOffsetAddress(pc, cpu.y)
-	case addrModeRelative:
-		return cpu.getRelativeAddress(pc)
-	case addrModeIndirect:
-		return cpu.getIndirectAddress(pc), false
-	case addrModeIndexedIndir:
-		return cpu.getIndexedIndirectAddress(pc, cpu.x), false
-	case addrModeIndirIndexed:
-		return cpu.getIndirectIndexedAddress(pc, cpu.y)
-	}
-	panic("invalid address mode")
-}
-
-func (cpu *cpu) getZeroPageAddress(pc uint16) uint16 {
-	address := cpu.sys.read(pc + 1)
-	return uint16(address)
-}
-
-func (cpu *cpu) getZeroPageOffsetAddress(pc uint16, offset uint8) uint16 {
-	zeroPageAddr := cpu.sys.read(pc + 1)
-	zeroPageAddr += offset
-	return uint16(zeroPageAddr)
-}
-
-func (cpu *cpu) getAbsoluteAddress(pc uint16) uint16 {
-	low := cpu.sys.read(pc + 1)
-	high := cpu.sys.read(pc + 2)
-	return uint16(high)<<8 | uint16(low)
-}
-
-func (cpu *cpu) getAbsoluteOffsetAddress(pc uint16, offset uint8) (uint16, bool) {
-	address := cpu.getAbsoluteAddress(pc)
-	offsetAddress := address + uint16(offset)
-	if address&0xFF00 != offsetAddress&0xFF00 {
-		return offsetAddress, true
-	} else {
-		return offsetAddress, false
-	}
-}
-
-func (cpu *cpu) getRelativeAddress(pc uint16) (uint16, bool) {
-	offset := int8(cpu.sys.read(pc + 1))
-	offsetAddress := int16(pc+2) + int16(offset)
-	address := uint16(offsetAddress)
-	if (pc+2)&0xFF00 != address&0xFF00 {
-		return address, true
-	} else {
-		return address, false
+// stackDummyRead is the read of the top of the stack that instructions which
+// pull from it do while the stack pointer is incremented
+func (cpu *cpu) stackDummyRead() {
+	cpu.sys.read(stackBase + uint16(cpu.sp))
+}
+
+// interrupt pushes the program counter and status and jumps to the address
+// in the vector, the same way brk does
+func (cpu *cpu) interrupt() {
+	switch cpu.cycle {
+	case 2:
+		cpu.sys.read(cpu.pc)
+	case 3:
+		cpu.stackPush(uint8(cpu.pc >> 8))
+	case 4:
+		cpu.stackPush(uint8(cpu.pc))
+	case 5:
+		cpu.stackPush(cpu.status & ^breakFlagMask | unusedFlagMask)
+		cpu.updateFlag(intDisableFlagMask, true)
+	case 6:
+		cpu.addr = uint16(cpu.sys.read(cpu.vector))
+	case 7:
+		cpu.pc = cpu.addr | uint16(cpu.sys.read(cpu.vector+1))<<8
+		cpu.finish()
 	}
 }
 
-func (cpu *cpu) getIndirectAddress(pc uint16) uint16 {
-	jumpAddress := cpu.getAbsoluteAddress(pc)
-	low := cpu.sys.read(jumpAddress)
+// branch reads the offset and jumps to it if taken is true, which takes an
+// extra cycle plus another to fix the high byte if the jump crosses a page
+func (cpu *cpu) branch(taken bool) {
+	switch cpu.cycle {
+	case 2:
+		cpu.value = cpu.fetch()
+		if !taken {
+			cpu.finish()
+			return
+		}
 
-	// the 6502 has a bug where it wraps incorrectly if the jump address is at
-	// a page boundary
-	var high uint8
-	if jumpAddress&0x00FF == 0x00FF {
-		high = cpu.sys.read(jumpAddress & 0xFF00)
-	} else {
-		high = cpu.sys.read(jumpAddress + 1)
+		// taken branches don't sample the interrupt lines here, so one that
+		// stays on the same page decides from what they were a cycle earlier
+		cpu.skipPoll = true
+	case 3:
+		cpu.sys.read(cpu.pc)
+		cpu.addr = uint16(int16(cpu.pc) + int16(int8(cpu.value)))
+		if cpu.addr&0xFF00 == cpu.pc&0xFF00 {
+			cpu.pc = cpu.addr
+			cpu.finish()
+			return
+		}
+		cpu.pc = cpu.pc&0xFF00 | cpu.addr&0x00FF
+	case 4:
+		cpu.sys.read(cpu.pc)
+		cpu.pc = cpu.addr
+		cpu.finish()
 	}
-
-	address := uint16(high)<<8 | uint16(low)
-	return address
 }
 
-func (cpu *cpu) getIndexedIndirectAddress(pc uint16, offset uint8) uint16 {
-	zeroPageAddr := cpu.sys.read(pc + 1)
-	zeroPageAddr += offset
-	low := cpu.sys.read(uint16(zeroPageAddr))
-	high := cpu.sys.read(uint16(zeroPageAddr + 1))
-	return uint16(high)<<8 | uint16(low)
-}
-
-func (cpu *cpu) getIndirectIndexedAddress(pc uint16, offset uint8) (uint16, bool) {
-	zeroPageAddr := cpu.sys.read(pc + 1)
-	low := cpu.sys.read(uint16(zeroPageAddr))
-	high := cpu.sys.read(uint16(zeroPageAddr + 1))
-	address := uint16(high)<<8 | uint16(low)
-	offsetAddress := address + uint16(offset)
-	if address&0xFF00 != offsetAddress&0xFF00 {
-		return offsetAddress, true
-	} else {
-		return offsetAddress, false
+// storeHighAnd stores value anded with one more than the high byte of the
+// base address, which is what ahx, shx, shy and tas do. when indexing
+// crosses a page the stored value also replaces the high byte of the address
+func (cpu *cpu) storeHighAnd(value uint8) {
+	baseHigh := uint8(cpu.unfixedAddr() >> 8)
+	cpu.value = value & (baseHigh + 1)
+	if cpu.pageCrossed {
+		cpu.addr = uint16(cpu.value)<<8 | cpu.addr&0x00FF
 	}
 }
 
 // add with carry
-func (cpu *cpu) adc(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-		if pageCrossed {
-			cpu.cycleDelay++
-		}
-	}
-
+func (cpu *cpu) adc() {
+	value := cpu.value
 	result := uint16(cpu.a) + uint16(value)
 	if cpu.testFlag(carryFlagMask) {
 		result++
@@ -269,81 +539,85 @@ func (cpu *cpu) adc(addrMode addressMode, pc uint16) {
 	cpu.a = uint8(result)
 }
 
+// store a and x and the high byte of the address plus one
+func (cpu *cpu) ahx() {
+	cpu.storeHighAnd(cpu.a & cpu.x)
+}
+
+// and with immediate and logical shift right
+func (cpu *cpu) alr() {
+	cpu.a &= cpu.value
+	cpu.updateFlag(carryFlagMask, cpu.a&0x01 > 0)
+	cpu.a >>= 1
+
+	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
+}
+
+// and with immediate and copy bit 7 to carry
+func (cpu *cpu) anc() {
+	cpu.a &= cpu.value
+
+	cpu.updateFlag(carryFlagMask, cpu.a&0x80 > 0)
+	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
+}
+
 // bitwise and
-func (cpu *cpu) and(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-		if pageCrossed {
-			cpu.cycleDelay++
-		}
-	}
+func (cpu *cpu) and() {
+	cpu.a &= cpu.value
+
+	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
+}
 
-	cpu.a &= value
+// and with immediate and rotate right, with carry and overflow coming from
+// bits 6 and 5 of the result
+func (cpu *cpu) arr() {
+	cpu.a &= cpu.value
+	cpu.a >>= 1
+	if cpu.testFlag(carryFlagMask) {
+		cpu.a |= 0x80
+	}
 
+	cpu.updateFlag(carryFlagMask, cpu.a&0x40 > 0)
+	cpu.updateFlag(overflowFlagMask, (cpu.a>>6^cpu.a>>5)&0x01 > 0)
 	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
 	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
 }
 
 // arithmetic shift left
-func (cpu *cpu) asl(addrMode addressMode, pc uint16) {
-	var value uint8
-	var address uint16
-	if addrMode == addrModeAccumulator {
-		value = cpu.a
-	} else {
-		address, _ = cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-	}
+func (cpu *cpu) asl() {
+	cpu.updateFlag(carryFlagMask, cpu.value&0x80 > 0)
+	cpu.value <<= 1
 
-	cpu.updateFlag(carryFlagMask, value&0x80 > 0)
-	value <<= 1
+	cpu.updateFlag(zeroFlagMask, cpu.value == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.value&0x80 > 0)
+}
 
-	cpu.updateFlag(zeroFlagMask, value == 0)
-	cpu.updateFlag(negativeFlagMask, value&0x80 > 0)
+// and x with a and subtract immediate without borrow into x
+func (cpu *cpu) axs() {
+	ax := cpu.a & cpu.x
+	cpu.x = ax - cpu.value
 
-	if addrMode == addrModeAccumulator {
-		cpu.a = value
-	} else {
-		cpu.sys.write(address, value)
-	}
+	cpu.updateFlag(carryFlagMask, ax >= cpu.value)
+	cpu.updateFlag(zeroFlagMask, cpu.x == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.x&0x80 > 0)
 }
 
 // branch if carry set
-func (cpu *cpu) bcs(addrMode addressMode, pc uint16) {
-	if !cpu.testFlag(carryFlagMask) {
-		return
-	}
-	address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	cpu.pc = address
-
-	cpu.cycleDelay++
-	if pageCrossed {
-		cpu.cycleDelay++
-	}
+func (cpu *cpu) bcs() {
+	cpu.branch(cpu.testFlag(carryFlagMask))
 }
 
 // branch if carry clear
-func (cpu *cpu) bcc(addrMode addressMode, pc uint16) {
-	if cpu.testFlag(carryFlagMask) {
-		return
-	}
-	address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	cpu.pc = address
-
-	cpu.cycleDelay++
-	if pageCrossed {
-		cpu.cycleDelay++
-	}
+func (cpu *cpu) bcc() {
+	cpu.branch(!cpu.testFlag(carryFlagMask))
 }
 
 // bit test
-func (cpu *cpu) bit(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.sys.read(address)
+func (cpu *cpu) bit() {
+	value := cpu.value
 	result := cpu.a & value
 
 	cpu.updateFlag(zeroFlagMask, result == 0)
@@ -352,203 +626,114 @@ func (cpu *cpu) bit(addrMode addressMode, pc uint16) {
 }
 
 // branch if minus
-func (cpu *cpu) bmi(addrMode addressMode, pc uint16) {
-	if !cpu.testFlag(negativeFlagMask) {
-		return
-	}
-	address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	cpu.pc = address
-
-	cpu.cycleDelay++
-	if pageCrossed {
-		cpu.cycleDelay++
-	}
+func (cpu *cpu) bmi() {
+	cpu.branch(cpu.testFlag(negativeFlagMask))
 }
 
 // branch if not equal
-func (cpu *cpu) bne(addrMode addressMode, pc uint16) {
-	if cpu.testFlag(zeroFlagMask) {
-		return
-	}
-	address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	cpu.pc = address
-
-	cpu.cycleDelay++
-	if pageCrossed {
-		cpu.cycleDelay++
-	}
+func (cpu *cpu) bne() {
+	cpu.branch(!cpu.testFlag(zeroFlagMask))
 }
 
 // branch if plus
-func (cpu *cpu) bpl(addrMode addressMode, pc uint16) {
-	if cpu.testFlag(negativeFlagMask) {
-		return
-	}
-	address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	cpu.pc = address
-
-	cpu.cycleDelay++
-	if pageCrossed {
-		cpu.cycleDelay++
+func (cpu *cpu) bpl() {
+	cpu.branch(!cpu.testFlag(negativeFlagMask))
+}
+
+// force break, the byte after the opcode is skipped
+func (cpu *cpu) brk() {
+	switch cpu.cycle {
+	case 2:
+		cpu.fetch()
+	case 3:
+		cpu.stackPush(uint8(cpu.pc >> 8))
+	case 4:
+		cpu.stackPush(uint8(cpu.pc))
+	case 5:
+		cpu.stackPush(cpu.status | unusedFlagMask | breakFlagMask)
+		cpu.updateFlag(intDisableFlagMask, true)
+	case 6:
+		cpu.addr = uint16(cpu.sys.read(irqVector))
+	case 7:
+		cpu.pc = cpu.addr | uint16(cpu.sys.read(irqVector+1))<<8
+		cpu.finish()
 	}
 }
 
-// force break
-func (cpu *cpu) brk(addrMode addressMode, pc uint16) {
-	pc += 2
-	oldPcLow := uint8(pc & 0x00FF)
-	oldPcHigh := uint8(pc & 0xFF00 >> 8)
-	cpu.stackPush(oldPcHigh)
-	cpu.stackPush(oldPcLow)
-	cpu.stackPush(cpu.status | unusedFlagMask | breakFlagMask)
-	newPcLow := cpu.sys.read(irqVector)
-	newPcHigh := cpu.sys.read(irqVector + 1)
-	cpu.pc = uint16(newPcHigh)<<8 | uint16(newPcLow)
-}
-
 // branch if overflow clear
-func (cpu *cpu) bvc(addrMode addressMode, pc uint16) {
-	if cpu.testFlag(overflowFlagMask) {
-		return
-	}
-	address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	cpu.pc = address
-
-	cpu.cycleDelay++
-	if pageCrossed {
-		cpu.cycleDelay++
-	}
+func (cpu *cpu) bvc() {
+	cpu.branch(!cpu.testFlag(overflowFlagMask))
 }
 
 // branch if overflow set
-func (cpu *cpu) bvs(addrMode addressMode, pc uint16) {
-	if !cpu.testFlag(overflowFlagMask) {
-		return
-	}
-	address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	cpu.pc = address
-
-	cpu.cycleDelay++
-	if pageCrossed {
-		cpu.cycleDelay++
-	}
+func (cpu *cpu) bvs() {
+	cpu.branch(cpu.testFlag(overflowFlagMask))
 }
 
 // branch if equal
-func (cpu *cpu) beq(addrMode addressMode, pc uint16) {
-	if !cpu.testFlag(zeroFlagMask) {
-		return
-	}
-	address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	cpu.pc = address
-
-	cpu.cycleDelay++
-	if pageCrossed {
-		cpu.cycleDelay++
-	}
+func (cpu *cpu) beq() {
+	cpu.branch(cpu.testFlag(zeroFlagMask))
 }
 
 // clear carry
-func (cpu *cpu) clc(addrMode addressMode, pc uint16) {
+func (cpu *cpu) clc() {
 	cpu.updateFlag(carryFlagMask, false)
 }
 
 // clear decimal
-func (cpu *cpu) cld(addrMode addressMode, pc uint16) {
+func (cpu *cpu) cld() {
 	cpu.updateFlag(decimalFlagMask, false)
 }
 
 // clear interrupt disable
-func (cpu *cpu) cli(addrMode addressMode, pc uint16) {
+func (cpu *cpu) cli() {
 	cpu.updateFlag(intDisableFlagMask, false)
 }
 
 // clear overflow
-func (cpu *cpu) clv(addrMode addressMode, pc uint16) {
+func (cpu *cpu) clv() {
 	cpu.updateFlag(overflowFlagMask, false)
 }
 
 // compare a
-func (cpu *cpu) cmp(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-		if pageCrossed {
-			cpu.cycleDelay++
-		}
-	}
-
-	result := cpu.a - value
-
-	cpu.updateFlag(carryFlagMask, cpu.a >= value)
-	cpu.updateFlag(zeroFlagMask, cpu.a == value)
-	cpu.updateFlag(negativeFlagMask, result&0x80 > 0)
+func (cpu *cpu) cmp() {
+	cpu.compare(cpu.a)
 }
 
 // compare x
-func (cpu *cpu) cpx(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, _ := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-	}
-
-	result := cpu.x - value
-
-	cpu.updateFlag(carryFlagMask, cpu.x >= value)
-	cpu.updateFlag(zeroFlagMask, cpu.x == value)
-	cpu.updateFlag(negativeFlagMask, result&0x80 > 0)
+func (cpu *cpu) cpx() {
+	cpu.compare(cpu.x)
 }
 
 // compare y
-func (cpu *cpu) cpy(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, _ := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-	}
+func (cpu *cpu) cpy() {
+	cpu.compare(cpu.y)
+}
 
-	result := cpu.y - value
+func (cpu *cpu) compare(register uint8) {
+	result := register - cpu.value
 
-	cpu.updateFlag(carryFlagMask, cpu.y >= value)
-	cpu.updateFlag(zeroFlagMask, cpu.y == value)
+	cpu.updateFlag(carryFlagMask, register >= cpu.value)
+	cpu.updateFlag(zeroFlagMask, register == cpu.value)
 	cpu.updateFlag(negativeFlagMask, result&0x80 > 0)
 }
 
 // decrement memory and compare a
-func (cpu *cpu) dcp(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.sys.read(address)
-	value--
-	cpu.sys.write(address, value)
-
-	result := cpu.a - value
-
-	cpu.updateFlag(carryFlagMask, cpu.a >= value)
-	cpu.updateFlag(zeroFlagMask, cpu.a == value)
-	cpu.updateFlag(negativeFlagMask, result&0x80 > 0)
+func (cpu *cpu) dcp() {
+	cpu.value--
+	cpu.cmp()
 }
 
 // decrement memory
-func (cpu *cpu) dec(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.sys.read(address)
-	value--
-	cpu.sys.write(address, value)
+func (cpu *cpu) dec() {
+	cpu.value--
 
-	cpu.updateFlag(zeroFlagMask, value == 0)
-	cpu.updateFlag(negativeFlagMask, value&0x80 > 0)
+	cpu.updateFlag(zeroFlagMask, cpu.value == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.value&0x80 > 0)
 }
 
 // decrement x
-func (cpu *cpu) dex(addrMode addressMode, pc uint16) {
+func (cpu *cpu) dex() {
 	cpu.x--
 
 	cpu.updateFlag(zeroFlagMask, cpu.x == 0)
@@ -556,7 +741,7 @@ func (cpu *cpu) dex(addrMode addressMode, pc uint16) {
 }
 
 // decrement y
-func (cpu *cpu) dey(addrMode addressMode, pc uint16) {
+func (cpu *cpu) dey() {
 	cpu.y--
 
 	cpu.updateFlag(zeroFlagMask, cpu.y == 0)
@@ -564,37 +749,23 @@ func (cpu *cpu) dey(addrMode addressMode, pc uint16) {
 }
 
 // bitwise exclusive or
-func (cpu *cpu) eor(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-		if pageCrossed {
-			cpu.cycleDelay++
-		}
-	}
-
-	cpu.a ^= value
+func (cpu *cpu) eor() {
+	cpu.a ^= cpu.value
 
 	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
 	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
 }
 
 // increment memory
-func (cpu *cpu) inc(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.sys.read(address)
-	value++
-	cpu.sys.write(address, value)
+func (cpu *cpu) inc() {
+	cpu.value++
 
-	cpu.updateFlag(zeroFlagMask, value == 0)
-	cpu.updateFlag(negativeFlagMask, value&0x80 > 0)
+	cpu.updateFlag(zeroFlagMask, cpu.value == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.value&0x80 > 0)
 }
 
 // increment x
-func (cpu *cpu) inx(addrMode addressMode, pc uint16) {
+func (cpu *cpu) inx() {
 	cpu.x++
 
 	cpu.updateFlag(zeroFlagMask, cpu.x == 0)
@@ -602,7 +773,7 @@ func (cpu *cpu) inx(addrMode addressMode, pc uint16) {
 }
 
 // increment y
-func (cpu *cpu) iny(addrMode addressMode, pc uint16) {
+func (cpu *cpu) iny() {
 	cpu.y++
 
 	cpu.updateFlag(zeroFlagMask, cpu.y == 0)
@@ -610,335 +781,270 @@ func (cpu *cpu) iny(addrMode addressMode, pc uint16) {
 }
 
 // increment memory and subtract with carry
-func (cpu *cpu) isb(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.sys.read(address)
-	value++
-	cpu.sys.write(address, value)
-
-	result := int16(cpu.a) - int16(value)
-	if !cpu.testFlag(carryFlagMask) {
-		result--
-	}
-
-	cpu.updateFlag(carryFlagMask, result >= 0)
-	cpu.updateFlag(zeroFlagMask, result == 0)
-	cpu.updateFlag(overflowFlagMask, (uint8(result)^cpu.a)&(uint8(result)^^value)&0x80 > 0)
-	cpu.updateFlag(negativeFlagMask, result&0x80 > 0)
+func (cpu *cpu) isb() {
+	cpu.value++
+	cpu.sbc()
+}
 
-	cpu.a = uint8(result)
+// halt the cpu, it keeps the jam opcode on the bus and ignores interrupts
+func (cpu *cpu) jam() {
+	cpu.pc--
+	cpu.sys.read(cpu.pc)
+	cpu.halted = true
+	cpu.finish()
 }
 
 // jump
-func (cpu *cpu) jmp(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	cpu.pc = address
+func (cpu *cpu) jmp() {
+	switch cpu.cycle {
+	case 2:
+		cpu.addr = uint16(cpu.fetch())
+	case 3:
+		cpu.addr |= uint16(cpu.fetch()) << 8
+		if cpu.instr.addrMode == addrModeAbsolute {
+			cpu.pc = cpu.addr
+			cpu.finish()
+		}
+	case 4:
+		cpu.value = cpu.sys.read(cpu.addr)
+	case 5:
+		// the 6502 has a bug where it wraps incorrectly if the jump address
+		// is at a page boundary
+		high := cpu.sys.read(cpu.addr&0xFF00 | uint16(uint8(cpu.addr)+1))
+		cpu.pc = uint16(high)<<8 | uint16(cpu.value)
+		cpu.finish()
+	}
 }
 
-// jump to subroutine
-func (cpu *cpu) jsr(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	pc += 2
-	low := uint8(pc & 0x00FF)
-	high := uint8((pc & 0xFF00) >> 8)
-	cpu.stackPush(high)
-	cpu.stackPush(low)
-	cpu.pc = address
+// jump to subroutine, the high byte of the address is read after the return
+// address is pushed
+func (cpu *cpu) jsr() {
+	switch cpu.cycle {
+	case 2:
+		cpu.addr = uint16(cpu.fetch())
+	case 3:
+		cpu.stackDummyRead()
+	case 4:
+		cpu.stackPush(uint8(cpu.pc >> 8))
+	case 5:
+		cpu.stackPush(uint8(cpu.pc))
+	case 6:
+		cpu.pc = cpu.addr | uint16(cpu.sys.read(cpu.pc))<<8
+		cpu.finish()
+	}
 }
 
-// load a and load x
-func (cpu *cpu) lax(addrMode addressMode, pc uint16) {
-	address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	if pageCrossed {
-		cpu.cycleDelay++
-	}
-	value := cpu.sys.read(address)
+// and with the stack pointer and load a, x and the stack pointer
+func (cpu *cpu) las() {
+	value := cpu.value & cpu.sp
 
 	cpu.a = value
 	cpu.x = value
+	cpu.sp = value
 
 	cpu.updateFlag(zeroFlagMask, value == 0)
 	cpu.updateFlag(negativeFlagMask, value&0x80 > 0)
 }
 
-// load a
-func (cpu *cpu) lda(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-		if pageCrossed {
-			cpu.cycleDelay++
-		}
-	}
+// load a and load x
+func (cpu *cpu) lax() {
+	cpu.a = cpu.value
+	cpu.x = cpu.value
 
-	cpu.a = value
+	cpu.updateFlag(zeroFlagMask, cpu.value == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.value&0x80 > 0)
+}
+
+// load a
+func (cpu *cpu) lda() {
+	cpu.a = cpu.value
 
 	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
 	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
 }
 
 // load x
-func (cpu *cpu) ldx(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-		if pageCrossed {
-			cpu.cycleDelay++
-		}
-	}
-
-	cpu.x = value
+func (cpu *cpu) ldx() {
+	cpu.x = cpu.value
 
 	cpu.updateFlag(zeroFlagMask, cpu.x == 0)
 	cpu.updateFlag(negativeFlagMask, cpu.x&0x80 > 0)
 }
 
 // load y
-func (cpu *cpu) ldy(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-		if pageCrossed {
-			cpu.cycleDelay++
-		}
-	}
-
-	cpu.y = value
+func (cpu *cpu) ldy() {
+	cpu.y = cpu.value
 
 	cpu.updateFlag(zeroFlagMask, cpu.y == 0)
 	cpu.updateFlag(negativeFlagMask, cpu.y&0x80 > 0)
 }
 
 // logical shift right
-func (cpu *cpu) lsr(addrMode addressMode, pc uint16) {
-	var value uint8
-	var address uint16
-	if addrMode == addrModeAccumulator {
-		value = cpu.a
-	} else {
-		address, _ = cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-	}
+func (cpu *cpu) lsr() {
+	cpu.updateFlag(carryFlagMask, cpu.value&0x01 > 0)
+	cpu.value >>= 1
 
-	cpu.updateFlag(carryFlagMask, value&0x01 > 0)
-	value >>= 1
-
-	cpu.updateFlag(zeroFlagMask, value == 0)
+	cpu.updateFlag(zeroFlagMask, cpu.value == 0)
 	cpu.updateFlag(negativeFlagMask, false)
-	if addrMode == addrModeAccumulator {
-		cpu.a = value
-	} else {
-		cpu.sys.write(address, value)
-	}
+}
+
+// load a and x with immediate and an unstable value
+func (cpu *cpu) lxa() {
+	cpu.value &= cpu.a | unstableMagic
+	cpu.lax()
 }
 
 // no operation
-func (cpu *cpu) nop(addrMode addressMode, pc uint16) {
-	_, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-	if pageCrossed {
-		cpu.cycleDelay++
-	}
+func (cpu *cpu) nop() {
 	// do nothing
 }
 
 // bitwise or
-func (cpu *cpu) ora(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-		if pageCrossed {
-			cpu.cycleDelay++
-		}
-	}
-
-	cpu.a |= value
+func (cpu *cpu) ora() {
+	cpu.a |= cpu.value
 
 	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
 	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
 }
 
 // push a
-func (cpu *cpu) pha(addrMode addressMode, pc uint16) {
-	cpu.stackPush(cpu.a)
+func (cpu *cpu) pha() {
+	switch cpu.cycle {
+	case 2:
+		cpu.sys.read(cpu.pc)
+	case 3:
+		cpu.stackPush(cpu.a)
+		cpu.finish()
+	}
 }
 
 // push processor status
-func (cpu *cpu) php(addrMode addressMode, pc uint16) {
-	cpu.stackPush(cpu.status | unusedFlagMask | breakFlagMask)
+func (cpu *cpu) php() {
+	switch cpu.cycle {
+	case 2:
+		cpu.sys.read(cpu.pc)
+	case 3:
+		cpu.stackPush(cpu.status | unusedFlagMask | breakFlagMask)
+		cpu.finish()
+	}
 }
 
 // pull a
-func (cpu *cpu) pla(addrMode addressMode, pc uint16) {
-	cpu.a = cpu.stackPop()
-
-	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
-	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
+func (cpu *cpu) pla() {
+	switch cpu.cycle {
+	case 2:
+		cpu.sys.read(cpu.pc)
+	case 3:
+		cpu.stackDummyRead()
+	case 4:
+		cpu.a = cpu.stackPop()
+
+		cpu.updateFlag(zeroFlagMask, cpu.a == 0)
+		cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
+		cpu.finish()
+	}
 }
 
 // pull processor status
-func (cpu *cpu) plp(addrMode addressMode, pc uint16) {
-	flags := cpu.stackPop()
-	cpu.status = flags & 0xCF
-	cpu.status |= unusedFlagMask
+func (cpu *cpu) plp() {
+	switch cpu.cycle {
+	case 2:
+		cpu.sys.read(cpu.pc)
+	case 3:
+		cpu.stackDummyRead()
+	case 4:
+		flags := cpu.stackPop()
+		cpu.status = flags & 0xCF
+		cpu.status |= unusedFlagMask
+		cpu.finish()
+	}
 }
 
 // rotate left and bitwise and
-func (cpu *cpu) rla(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.sys.read(address)
-
-	carry := cpu.testFlag(carryFlagMask)
-	cpu.updateFlag(carryFlagMask, value&0x80 > 0)
-
-	value <<= 1
-	if carry {
-		value |= 0x01
-	}
-
-	cpu.sys.write(address, value)
-
-	cpu.a &= value
-
-	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
-	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
+func (cpu *cpu) rla() {
+	cpu.rol()
+	cpu.and()
 }
 
 // rotate left
-func (cpu *cpu) rol(addrMode addressMode, pc uint16) {
-	var value uint8
-	var address uint16
-	if addrMode == addrModeAccumulator {
-		value = cpu.a
-	} else {
-		address, _ = cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-	}
-
+func (cpu *cpu) rol() {
 	carry := cpu.testFlag(carryFlagMask)
-	cpu.updateFlag(carryFlagMask, value&0x80 > 0)
-	value <<= 1
+	cpu.updateFlag(carryFlagMask, cpu.value&0x80 > 0)
+	cpu.value <<= 1
 
 	if carry {
-		value |= 0x01
+		cpu.value |= 0x01
 	}
 
-	cpu.updateFlag(zeroFlagMask, value == 0)
-	cpu.updateFlag(negativeFlagMask, value&0x80 > 0)
-
-	if addrMode == addrModeAccumulator {
-		cpu.a = value
-	} else {
-		cpu.sys.write(address, value)
-	}
+	cpu.updateFlag(zeroFlagMask, cpu.value == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.value&0x80 > 0)
 }
 
 // rotate right
-func (cpu *cpu) ror(addrMode addressMode, pc uint16) {
-	var value uint8
-	var address uint16
-	if addrMode == addrModeAccumulator {
-		value = cpu.a
-	} else {
-		address, _ = cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-	}
-
+func (cpu *cpu) ror() {
 	carry := cpu.testFlag(carryFlagMask)
-	cpu.updateFlag(carryFlagMask, value&0x01 > 0)
+	cpu.updateFlag(carryFlagMask, cpu.value&0x01 > 0)
 
-	value >>= 1
+	cpu.value >>= 1
 	if carry {
-		value |= 0x80
+		cpu.value |= 0x80
 	}
 
-	cpu.updateFlag(zeroFlagMask, value == 0)
-	cpu.updateFlag(negativeFlagMask, value&0x80 > 0)
-
-	if addrMode == addrModeAccumulator {
-		cpu.a = value
-	} else {
-		cpu.sys.write(address, value)
-	}
+	cpu.updateFlag(zeroFlagMask, cpu.value == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.value&0x80 > 0)
 }
 
 // rotate right and add with carry
-func (cpu *cpu) rra(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.sys.read(address)
-
-	carry := cpu.testFlag(carryFlagMask)
-	cpu.updateFlag(carryFlagMask, value&0x01 > 0)
-
-	value >>= 1
-	if carry {
-		value |= 0x80
-	}
-
-	cpu.sys.write(address, value)
-
-	result := uint16(cpu.a) + uint16(value)
-	if cpu.testFlag(carryFlagMask) {
-		result++
-	}
-
-	cpu.updateFlag(carryFlagMask, result > 255)
-	cpu.updateFlag(zeroFlagMask, uint8(result) == 0)
-	cpu.updateFlag(overflowFlagMask, (uint8(result)^cpu.a)&(uint8(result)^value)&0x80 > 0)
-	cpu.updateFlag(negativeFlagMask, result&0x80 > 0)
-
-	cpu.a = uint8(result)
+func (cpu *cpu) rra() {
+	cpu.ror()
+	cpu.adc()
 }
 
 // return from interrupt
-func (cpu *cpu) rti(addrMode addressMode, pc uint16) {
-	flags := cpu.stackPop()
-	low := cpu.stackPop()
-	high := cpu.stackPop()
-	cpu.status = flags & 0xCF
-	cpu.status |= unusedFlagMask
-	cpu.pc = uint16(high)<<8 | uint16(low)
+func (cpu *cpu) rti() {
+	switch cpu.cycle {
+	case 2:
+		cpu.sys.read(cpu.pc)
+	case 3:
+		cpu.stackDummyRead()
+	case 4:
+		flags := cpu.stackPop()
+		cpu.status = flags & 0xCF
+		cpu.status |= unusedFlagMask
+	case 5:
+		cpu.addr = uint16(cpu.stackPop())
+	case 6:
+		cpu.pc = cpu.addr | uint16(cpu.stackPop())<<8
+		cpu.finish()
+	}
 }
 
 // return from subroutine
-func (cpu *cpu) rts(addrMode addressMode, pc uint16) {
-	low := cpu.stackPop()
-	high := cpu.stackPop()
-	address := uint16(high)<<8 | uint16(low)
-	cpu.pc = address + 1
+func (cpu *cpu) rts() {
+	switch cpu.cycle {
+	case 2:
+		cpu.sys.read(cpu.pc)
+	case 3:
+		cpu.stackDummyRead()
+	case 4:
+		cpu.addr = uint16(cpu.stackPop())
+	case 5:
+		cpu.addr |= uint16(cpu.stackPop()) << 8
+	case 6:
+		cpu.sys.read(cpu.addr)
+		cpu.pc = cpu.addr + 1
+		cpu.finish()
+	}
 }
 
 // store a and x
-func (cpu *cpu) sax(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.a & cpu.x
-	cpu.sys.write(address, value)
+func (cpu *cpu) sax() {
+	cpu.value = cpu.a & cpu.x
 }
 
 // subtract with carry
-func (cpu *cpu) sbc(addrMode addressMode, pc uint16) {
-	var value uint8
-	if addrMode == addrModeImmediate {
-		value = cpu.sys.read(pc + 1)
-	} else {
-		address, pageCrossed := cpu.mustGetAddress(addrMode, pc)
-		value = cpu.sys.read(address)
-		if pageCrossed {
-			cpu.cycleDelay++
-		}
-	}
-
+func (cpu *cpu) sbc() {
+	value := cpu.value
 	result := int16(cpu.a) - int16(value)
 	if !cpu.testFlag(carryFlagMask) {
 		result--
@@ -953,70 +1059,66 @@ func (cpu *cpu) sbc(addrMode addressMode, pc uint16) {
 }
 
 // set carry
-func (cpu *cpu) sec(addrMode addressMode, pc uint16) {
+func (cpu *cpu) sec() {
 	cpu.updateFlag(carryFlagMask, true)
 }
 
 // set decimal
-func (cpu *cpu) sed(addrMode addressMode, pc uint16) {
+func (cpu *cpu) sed() {
 	cpu.updateFlag(decimalFlagMask, true)
 }
 
 // set interrupt disable
-func (cpu *cpu) sei(addrMode addressMode, pc uint16) {
+func (cpu *cpu) sei() {
 	cpu.updateFlag(intDisableFlagMask, true)
 }
 
-// arithmetic shift left and bitwise or
-func (cpu *cpu) slo(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.sys.read(address)
-
-	cpu.updateFlag(carryFlagMask, value&0x80 > 0)
-	value <<= 1
+// store x and the high byte of the address plus one
+func (cpu *cpu) shx() {
+	cpu.storeHighAnd(cpu.x)
+}
 
-	cpu.sys.write(address, value)
-	cpu.a |= value
+// store y and the high byte of the address plus one
+func (cpu *cpu) shy() {
+	cpu.storeHighAnd(cpu.y)
+}
 
-	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
-	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
+// arithmetic shift left and bitwise or
+func (cpu *cpu) slo() {
+	cpu.asl()
+	cpu.ora()
 }
 
 // logical shift right and bitwise exclusive or
-func (cpu *cpu) sre(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	value := cpu.sys.read(address)
-
-	cpu.updateFlag(carryFlagMask, value&0x01 > 0)
-	value >>= 1
-	cpu.sys.write(address, value)
-
-	cpu.a ^= value
-
-	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
-	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
+func (cpu *cpu) sre() {
+	cpu.lsr()
+	cpu.eor()
 }
 
 // store a
-func (cpu *cpu) sta(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	cpu.sys.write(address, cpu.a)
+func (cpu *cpu) sta() {
+	cpu.value = cpu.a
 }
 
 // store x
-func (cpu *cpu) stx(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	cpu.sys.write(address, cpu.x)
+func (cpu *cpu) stx() {
+	cpu.value = cpu.x
 }
 
 // store y
-func (cpu *cpu) sty(addrMode addressMode, pc uint16) {
-	address, _ := cpu.mustGetAddress(addrMode, pc)
-	cpu.sys.write(address, cpu.y)
+func (cpu *cpu) sty() {
+	cpu.value = cpu.y
+}
+
+// transfer a and x to the stack pointer and store it and the high byte of
+// the address plus one
+func (cpu *cpu) tas() {
+	cpu.sp = cpu.a & cpu.x
+	cpu.storeHighAnd(cpu.sp)
 }
 
 // transfer a to x
-func (cpu *cpu) tax(addrMode addressMode, pc uint16) {
+func (cpu *cpu) tax() {
 	cpu.x = cpu.a
 
 	cpu.updateFlag(zeroFlagMask, cpu.x == 0)
@@ -1024,7 +1126,7 @@ func (cpu *cpu) tax(addrMode addressMode, pc uint16) {
 }
 
 // transfer a to y
-func (cpu *cpu) tay(addrMode addressMode, pc uint16) {
+func (cpu *cpu) tay() {
 	cpu.y = cpu.a
 
 	cpu.updateFlag(zeroFlagMask, cpu.y == 0)
@@ -1032,7 +1134,7 @@ func (cpu *cpu) tay(addrMode addressMode, pc uint16) {
 }
 
 // transfer stack pointer to x
-func (cpu *cpu) tsx(addrMode addressMode, pc uint16) {
+func (cpu *cpu) tsx() {
 	cpu.x = cpu.sp
 
 	cpu.updateFlag(zeroFlagMask, cpu.x == 0)
@@ -1040,7 +1142,7 @@ func (cpu *cpu) tsx(addrMode addressMode, pc uint16) {
 }
 
 // transfer x to a
-func (cpu *cpu) txa(addrMode addressMode, pc uint16) {
+func (cpu *cpu) txa() {
 	cpu.a = cpu.x
 
 	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
@@ -1048,14 +1150,22 @@ func (cpu *cpu) txa(addrMode addressMode, pc uint16) {
 }
 
 // transfer x to stack pointer
-func (cpu *cpu) txs(addrMode addressMode, pc uint16) {
+func (cpu *cpu) txs() {
 	cpu.sp = cpu.x
 }
 
 // transfer y to a
-func (cpu *cpu) tya(addrMode addressMode, pc uint16) {
+func (cpu *cpu) tya() {
 	cpu.a = cpu.y
 
 	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
 	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
 }
+
+// bitwise and of a, x and immediate and an unstable value
+func (cpu *cpu) xaa() {
+	cpu.a = (cpu.a | unstableMagic) & cpu.x & cpu.value
+
+	cpu.updateFlag(zeroFlagMask, cpu.a == 0)
+	cpu.updateFlag(negativeFlagMask, cpu.a&0x80 > 0)
+}
@@ -0,0 +1,301 @@
+package nes
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testProgramAddr uint16 = 0xC000
+
+// newTestSystem loads program into an nrom cartridge at $C000, where the
+// reset vector points, followed by a jam opcode to stop the cpu
+func newTestSystem(t *testing.T, program []uint8) *System {
+	t.Helper()
+	rom := make([]uint8, headerSize+16384+8192)
+	copy(rom, []uint8{'N', 'E', 'S', 0x1A, 1, 1})
+	prg := rom[headerSize : headerSize+16384]
+	copy(prg, program)
+	prg[len(program)] = 0x02
+	binary.LittleEndian.PutUint16(prg[resetVector&0x3FFF:], testProgramAddr)
+
+	path := filepath.Join(t.TempDir(), "test.nes")
+	err := os.WriteFile(path, rom, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cartridge, err := NewCartridge(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSystem(nil, cartridge)
+}
+
+// runCpu clocks the cpu by itself until it halts
+func runCpu(t *testing.T, sys *System) {
+	t.Helper()
+	for range 10000 {
+		if _, halted := sys.CpuHalted(); halted {
+			return
+		}
+		sys.cpu.Clock()
+	}
+	t.Fatal("cpu never halted")
+}
+
+func TestUnofficialOpcodes(t *testing.T) {
+	const flagsMask = carryFlagMask | zeroFlagMask | overflowFlagMask | negativeFlagMask
+
+	tests := []struct {
+		name    string
+		program []uint8
+		a       uint8
+		x       uint8
+		y       uint8
+		sp      uint8
+		flags   uint8
+		mem     map[uint16]uint8
+	}{
+		{
+			"slo", []uint8{0xA9, 0x81, 0x85, 0x10, 0xA9, 0x41, 0x07, 0x10},
+			0x43, 0x00, 0x00, 0xFD, carryFlagMask, map[uint16]uint8{0x10: 0x02},
+		},
+		{
+			"rla", []uint8{0xA9, 0x40, 0x85, 0x10, 0x38, 0xA9, 0xFF, 0x27, 0x10},
+			0x81, 0x00, 0x00, 0xFD, negativeFlagMask, map[uint16]uint8{0x10: 0x81},
+		},
+		{
+			"sre", []uint8{0xA9, 0x03, 0x85, 0x10, 0xA9, 0xFF, 0x47, 0x10},
+			0xFE, 0x00, 0x00, 0xFD, carryFlagMask | negativeFlagMask, map[uint16]uint8{0x10: 0x01},
+		},
+		{
+			"rra", []uint8{0xA9, 0x03, 0x85, 0x10, 0x18, 0xA9, 0x10, 0x67, 0x10},
+			0x12, 0x00, 0x00, 0xFD, 0, map[uint16]uint8{0x10: 0x01},
+		},
+		{
+			"sax", []uint8{0xA9, 0xF0, 0xA2, 0x3C, 0x87, 0x10},
+			0xF0, 0x3C, 0x00, 0xFD, 0, map[uint16]uint8{0x10: 0x30},
+		},
+		{
+			"lax", []uint8{0xA9, 0x8C, 0x85, 0x10, 0xA9, 0x00, 0xA7, 0x10},
+			0x8C, 0x8C, 0x00, 0xFD, negativeFlagMask, nil,
+		},
+		{
+			"dcp", []uint8{0xA9, 0x05, 0x85, 0x10, 0xA9, 0x04, 0xC7, 0x10},
+			0x04, 0x00, 0x00, 0xFD, carryFlagMask | zeroFlagMask, map[uint16]uint8{0x10: 0x04},
+		},
+		{
+			"isb", []uint8{0xA9, 0x04, 0x85, 0x10, 0x38, 0xA9, 0x10, 0xE7, 0x10},
+			0x0B, 0x00, 0x00, 0xFD, carryFlagMask, map[uint16]uint8{0x10: 0x05},
+		},
+		{
+			"anc", []uint8{0xA9, 0xF0, 0x0B, 0xC3},
+			0xC0, 0x00, 0x00, 0xFD, carryFlagMask | negativeFlagMask, nil,
+		},
+		{
+			"alr", []uint8{0xA9, 0xFF, 0x4B, 0x81},
+			0x40, 0x00, 0x00, 0xFD, carryFlagMask, nil,
+		},
+		{
+			"arr", []uint8{0x38, 0xA9, 0xFF, 0x6B, 0xFF},
+			0xFF, 0x00, 0x00, 0xFD, carryFlagMask | negativeFlagMask, nil,
+		},
+		{
+			"arr overflow", []uint8{0x18, 0xA9, 0xFF, 0x6B, 0x80},
+			0x40, 0x00, 0x00, 0xFD, carryFlagMask | overflowFlagMask, nil,
+		},
+		{
+			"axs", []uint8{0xA9, 0xF0, 0xA2, 0xFF, 0xCB, 0x10},
+			0xF0, 0xE0, 0x00, 0xFD, carryFlagMask | negativeFlagMask, nil,
+		},
+		{
+			"lxa", []uint8{0xA9, 0x00, 0xAB, 0xFF},
+			unstableMagic, unstableMagic, 0x00, 0xFD, negativeFlagMask, nil,
+		},
+		{
+			"xaa", []uint8{0xA9, 0x11, 0xA2, 0xFF, 0x8B, 0x0F},
+			0x0F, 0xFF, 0x00, 0xFD, 0, nil,
+		},
+		{
+			"las", []uint8{0xA9, 0xF3, 0x8D, 0x10, 0x03, 0xA0, 0x10, 0xBB, 0x00, 0x03},
+			0xF1, 0xF1, 0x10, 0xF1, negativeFlagMask, nil,
+		},
+		{
+			"shx", []uint8{0xA2, 0xFF, 0xA0, 0x01, 0x9E, 0x00, 0x03},
+			0x00, 0xFF, 0x01, 0xFD, 0, map[uint16]uint8{0x0301: 0x04},
+		},
+		{
+			// crossing a page replaces the high byte of the address with the
+			// stored value
+			"shx page cross", []uint8{0xA2, 0x05, 0xA0, 0x20, 0x9E, 0xF0, 0x02},
+			0x00, 0x05, 0x20, 0xFD, 0, map[uint16]uint8{0x0110: 0x01, 0x0310: 0x00},
+		},
+		{
+			"shy", []uint8{0xA0, 0xFF, 0xA2, 0x01, 0x9C, 0x00, 0x03},
+			0x00, 0x01, 0xFF, 0xFD, 0, map[uint16]uint8{0x0301: 0x04},
+		},
+		{
+			"tas", []uint8{0xA9, 0xFF, 0xA2, 0x0F, 0xA0, 0x01, 0x9B, 0x00, 0x03},
+			0xFF, 0x0F, 0x01, 0x0F, 0, map[uint16]uint8{0x0301: 0x04},
+		},
+		{
+			"ahx", []uint8{0xA9, 0xFF, 0xA2, 0x0F, 0xA0, 0x01, 0x9F, 0x00, 0x03},
+			0xFF, 0x0F, 0x01, 0xFD, 0, map[uint16]uint8{0x0301: 0x04},
+		},
+		{
+			"sbc", []uint8{0x38, 0xA9, 0x10, 0xEB, 0x01},
+			0x0F, 0x00, 0x00, 0xFD, carryFlagMask, nil,
+		},
+		{
+			"nops", []uint8{0x80, 0xFF, 0x04, 0x10, 0x1C, 0x00, 0x03, 0x1A, 0xA9, 0x55},
+			0x55, 0x00, 0x00, 0xFD, 0, nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sys := newTestSystem(t, test.program)
+			runCpu(t, sys)
+
+			cpu := sys.cpu
+			if cpu.a != test.a || cpu.x != test.x || cpu.y != test.y || cpu.sp != test.sp {
+				t.Errorf("got a=%02X x=%02X y=%02X sp=%02X, want a=%02X x=%02X y=%02X sp=%02X",
+					cpu.a, cpu.x, cpu.y, cpu.sp, test.a, test.x, test.y, test.sp)
+			}
+			if flags := cpu.status & flagsMask; flags != test.flags {
+				t.Errorf("got flags %08b, want %08b", flags, test.flags)
+			}
+			for addr, want := range test.mem {
+				if data := sys.cpuRam[addr]; data != want {
+					t.Errorf("$%02X at $%04X, want $%02X", data, addr, want)
+				}
+			}
+
+			// every instruction should have been the right length to land on
+			// the jam at the end
+			addr, _ := sys.CpuHalted()
+			if want := testProgramAddr + uint16(len(test.program)); addr != want {
+				t.Errorf("halted at $%04X, want $%04X", addr, want)
+			}
+		})
+	}
+}
+
+// setIrqHandler copies handler to $E000 and points the irq vector at it
+func setIrqHandler(sys *System, handler []uint8) {
+	prg := sys.cartridge.board.ProgramRom
+	copy(prg[0x2000:], handler)
+	binary.LittleEndian.PutUint16(prg[irqVector&0x3FFF:], 0xE000)
+}
+
+func TestDummyAccesses(t *testing.T) {
+	// point the ppu at $2400 so every access to $2007 moves it along one
+	setPpuAddr := []uint8{0xA9, 0x24, 0x8D, 0x06, 0x20, 0xA9, 0x00, 0x8D, 0x06, 0x20}
+
+	tests := []struct {
+		name    string
+		program []uint8
+		want    uint16
+	}{
+		{"absolute x", []uint8{0xA2, 0x01, 0xBD, 0x06, 0x20}, 0x2401},
+		{"absolute x page cross", []uint8{0xA2, 0x10, 0xBD, 0xF7, 0x20}, 0x2402},
+		{"store absolute x", []uint8{0xA2, 0x10, 0x9D, 0xF7, 0x20}, 0x2402},
+		{"read modify write", []uint8{0xEE, 0x07, 0x20}, 0x2403},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sys := newTestSystem(t, append(setPpuAddr, test.program...))
+			runCpu(t, sys)
+			if sys.ppu.vramAddr != test.want {
+				t.Errorf("ppu address $%04X, want $%04X", sys.ppu.vramAddr, test.want)
+			}
+		})
+	}
+}
+
+func TestInterruptLatency(t *testing.T) {
+	// a branch at the end of a page that jumps to the next one
+	pageCross := make([]uint8, 0x106)
+	for i := range pageCross {
+		pageCross[i] = 0xEA
+	}
+	copy(pageCross[0xFC:], []uint8{0xD0, 0x04})
+	pageCross = append(pageCross, 0xE8, 0xE8)
+
+	tests := []struct {
+		name       string
+		program    []uint8
+		intDisable bool
+		irqFrom    uint8
+		want       uint8
+	}{
+		// the interrupt waits an instruction after cli and plp since the
+		// flag was still set when it was latched
+		{"cli", []uint8{0x58, 0xE8, 0xE8, 0xE8}, true, 0x58, 1},
+		{"plp", []uint8{0xA9, 0x00, 0x48, 0x28, 0xE8, 0xE8, 0xE8}, true, 0x28, 1},
+		{"branch", []uint8{0xD0, 0x00, 0xE8, 0xE8, 0xE8}, false, 0xD0, 1},
+		{"branch page cross", pageCross, false, 0xD0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sys := newTestSystem(t, test.program)
+			setIrqHandler(sys, []uint8{0x86, 0x10, 0x02})
+			sys.cpuRam[0x10] = 0xFF
+			sys.cpu.updateFlag(intDisableFlagMask, test.intDisable)
+
+			// hold the irq line from the second cycle of the instruction
+			asserted := false
+			for range 10000 {
+				if _, halted := sys.CpuHalted(); halted {
+					break
+				}
+				asserted = asserted || sys.cpu.cycle == 1 && sys.cpu.instr == &opcodes[test.irqFrom]
+				if asserted {
+					sys.cpu.Irq()
+				}
+				sys.cpu.Clock()
+			}
+			if x := sys.cpuRam[0x10]; x != test.want {
+				t.Errorf("x was %d at the irq, want %d", x, test.want)
+			}
+		})
+	}
+}
+
+func TestOamDma(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []uint8
+		cycles  int
+	}{
+		{"write on an even cycle", []uint8{0xA9, 0x02, 0x8D, 0x14, 0x40}, 513},
+		{"write on an odd cycle", []uint8{0xA5, 0x00, 0xA9, 0x02, 0x8D, 0x14, 0x40}, 514},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sys := newTestSystem(t, test.program)
+			for i := range 256 {
+				sys.cpuRam[0x200+i] = uint8(i) ^ 0x5A
+			}
+
+			cycles := 0
+			for range 10000 {
+				if _, halted := sys.CpuHalted(); halted {
+					break
+				}
+				if sys.cpu.oamDma {
+					cycles++
+				}
+				sys.cpu.Clock()
+			}
+			if cycles != test.cycles {
+				t.Errorf("dma took %d cycles, want %d", cycles, test.cycles)
+			}
+			for i := range 256 {
+				if data := sys.ppu.oamMem[i]; data != uint8(i)^0x5A {
+					t.Fatalf("$%02X in oam at %d, want $%02X", data, i, uint8(i)^0x5A)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package nes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/theaaronruss/nes-emulator/internal/mapper"
+)
+
+const (
+	fdsHeaderSize     int = 16
+	fdsBiosSize       int = 8192
+	fdsProgramRamSize int = 32768
+)
+
+// fwnes adds a 16 byte header to .fds images that starts with this
+var fdsHeaderMagic = []byte("FDS\x1A")
+
+// NewFdsCartridge loads a famicom disk system image, with or without the fwnes
+// header, and the disk system's bios. sides written by the game are kept in a
+// save file next to the image and used instead of the image's sides when it
+// exists
+func NewFdsCartridge(imagePath string, biosPath string) (*Cartridge, error) {
+	const errorMessage = "failed to read fds image: %w"
+
+	contents, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf(errorMessage, err)
+	}
+	sides, err := parseFdsSides(contents)
+	if err != nil {
+		return nil, fmt.Errorf(errorMessage, err)
+	}
+
+	cartridge := &Cartridge{savePath: savePath(imagePath)}
+	saved, err := os.ReadFile(cartridge.savePath)
+	if err == nil && len(saved) == len(sides)*mapper.FdsSideSize {
+		sides, _ = parseFdsSides(saved)
+	}
+
+	bios, err := os.ReadFile(biosPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fds bios: %w", err)
+	}
+	if len(bios) != fdsBiosSize {
+		return nil, errors.New("fds bios must be 8k")
+	}
+
+	cartridge.board.ProgramRom = bios
+	cartridge.board.ProgramRam = make([]uint8, fdsProgramRamSize)
+	cartridge.board.CharacterData = make([]uint8, characterRamSize)
+	cartridge.board.CharacterRam = true
+	cartridge.fds = mapper.NewFds(&cartridge.board, sides)
+	cartridge.useMapper(cartridge.fds)
+	return cartridge, nil
+}
+
+func parseFdsSides(contents []uint8) ([][]uint8, error) {
+	sideCount := len(contents) / mapper.FdsSideSize
+	if bytes.HasPrefix(contents, fdsHeaderMagic) {
+		if len(contents) < fdsHeaderSize {
+			return nil, errors.New("unexpected end of file")
+		}
+		sideCount = int(contents[4])
+		contents = contents[fdsHeaderSize:]
+	}
+	if sideCount < 1 || len(contents) < sideCount*mapper.FdsSideSize {
+		return nil, errors.New("unexpected end of file")
+	}
+
+	sides := make([][]uint8, sideCount)
+	for i := range sides {
+		sides[i] = contents[i*mapper.FdsSideSize : (i+1)*mapper.FdsSideSize]
+	}
+	return sides, nil
+}
+
+// DiskSideCount returns the number of disk sides, which is 0 for cartridges
+// that aren't disk system images
+func (cartridge *Cartridge) DiskSideCount() int {
+	if cartridge.fds == nil {
+		return 0
+	}
+	return cartridge.fds.DiskSideCount()
+}
+
+// DiskSide returns the inserted disk side, or -1 when it is ejected
+func (cartridge *Cartridge) DiskSide() int {
+	if cartridge.fds == nil {
+		return -1
+	}
+	return cartridge.fds.DiskSide()
+}
+
+func (cartridge *Cartridge) InsertDisk(side int) {
+	if cartridge.fds != nil {
+		cartridge.fds.InsertDisk(side)
+	}
+}
+
+func (cartridge *Cartridge) EjectDisk() {
+	if cartridge.fds != nil {
+		cartridge.fds.EjectDisk()
+	}
+}
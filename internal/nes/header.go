@@ -0,0 +1,186 @@
+package nes
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/theaaronruss/nes-emulator/internal/mapper"
+)
+
+const (
+	headerSize  int = 16
+	trainerSize int = 512
+
+	// the exponent form of a nes 2.0 rom size can describe far more than any
+	// rom holds, this allows up to 1g times the multiplier
+	nes20MaxSizeExponent uint8 = 30
+)
+
+var headerMagic = []byte("NES\x1A")
+
+// header flag bit masks
+const (
+	headerVertical   uint8 = 0x01
+	headerBattery    uint8 = 0x02
+	headerTrainer    uint8 = 0x04
+	headerFourScreen uint8 = 0x08
+	headerNes20      uint8 = 0x0C
+	headerConsole    uint8 = 0x03
+)
+
+// Timing is the console region a rom was made for
+type Timing int
+
+const (
+	TimingNtsc Timing = iota
+	TimingPal
+	TimingMultiRegion
+	TimingDendy
+)
+
+// ConsoleType is the kind of console a rom runs on
+type ConsoleType int
+
+const (
+	ConsoleNes ConsoleType = iota
+	ConsoleVsSystem
+	ConsolePlaychoice
+	ConsoleExtended
+)
+
+// Header is what the ines or nes 2.0 header of a rom says about the
+// cartridge. sizes are in bytes, and ram sizes that an ines header can't
+// give are filled in with the usual sizes
+type Header struct {
+	Nes20              bool
+	Mapper             int
+	Submapper          int
+	ProgramRomSize     int
+	CharacterRomSize   int
+	ProgramRamSize     int
+	ProgramNvramSize   int
+	CharacterRamSize   int
+	CharacterNvramSize int
+	Mirroring          mapper.Mirroring
+	FourScreen         bool
+	Battery            bool
+	Trainer            bool
+	Timing             Timing
+	ConsoleType        ConsoleType
+
+	// ConsoleDetail is byte 13 of a nes 2.0 header, the vs. system ppu and
+	// hardware types or the extended console type
+	ConsoleDetail   int
+	MiscRomCount    int
+	ExpansionDevice int
+}
+
+func parseHeader(data []uint8) (Header, error) {
+	if len(data) < headerSize {
+		return Header{}, errors.New("unexpected end of file")
+	}
+	if !bytes.HasPrefix(data, headerMagic) {
+		return Header{}, errors.New("not an ines rom")
+	}
+
+	header := Header{
+		Mirroring:   mapper.MirrorHorizontal,
+		FourScreen:  data[6]&headerFourScreen > 0,
+		Battery:     data[6]&headerBattery > 0,
+		Trainer:     data[6]&headerTrainer > 0,
+		ConsoleType: ConsoleType(data[7] & headerConsole),
+	}
+	switch {
+	case header.FourScreen:
+		header.Mirroring = mapper.MirrorFourScreen
+	case data[6]&headerVertical > 0:
+		header.Mirroring = mapper.MirrorVertical
+	}
+
+	if data[7]&headerNes20 == 0x08 {
+		err := header.parseNes20(data)
+		if err != nil {
+			return Header{}, err
+		}
+	} else {
+		header.parseInes(data)
+	}
+	return header, nil
+}
+
+func (header *Header) parseInes(data []uint8) {
+	// old tools wrote their name over bytes 7-15, in which case bytes 7-9
+	// can't be trusted for the upper bits of the mapper number, the prg ram
+	// size or the timing
+	programRamChunks := 0
+	header.Mapper = int(data[6] >> 4)
+	if bytes.Equal(data[12:16], []uint8{0, 0, 0, 0}) {
+		header.Mapper |= int(data[7] & 0xF0)
+		programRamChunks = int(data[8])
+		if data[9]&0x01 > 0 {
+			header.Timing = TimingPal
+		}
+	} else {
+		header.ConsoleType = ConsoleNes
+	}
+
+	header.ProgramRomSize = int(data[4]) * programRomChunkSize
+	header.CharacterRomSize = int(data[5]) * characterRomChunkSize
+
+	// a size of 0 means 8k, which is what most games that need ram have
+	programRamSize := max(1, programRamChunks) * programRamChunkSize
+	if header.Battery {
+		header.ProgramNvramSize = programRamSize
+	} else {
+		header.ProgramRamSize = programRamSize
+	}
+	if header.CharacterRomSize == 0 {
+		header.CharacterRamSize = characterRamSize
+	}
+}
+
+func (header *Header) parseNes20(data []uint8) error {
+	var err error
+	header.Nes20 = true
+	header.Mapper = int(data[6]>>4) | int(data[7]&0xF0) | int(data[8]&0x0F)<<8
+	header.Submapper = int(data[8] >> 4)
+	header.ProgramRomSize, err = nes20RomSize(data[4], data[9]&0x0F, programRomChunkSize)
+	if err != nil {
+		return err
+	}
+	header.CharacterRomSize, err = nes20RomSize(data[5], data[9]>>4, characterRomChunkSize)
+	if err != nil {
+		return err
+	}
+	header.ProgramRamSize = nes20RamSize(data[10] & 0x0F)
+	header.ProgramNvramSize = nes20RamSize(data[10] >> 4)
+	header.CharacterRamSize = nes20RamSize(data[11] & 0x0F)
+	header.CharacterNvramSize = nes20RamSize(data[11] >> 4)
+	header.Timing = Timing(data[12] & 0x03)
+	header.ConsoleDetail = int(data[13])
+	header.MiscRomCount = int(data[14] & 0x03)
+	header.ExpansionDevice = int(data[15] & 0x3F)
+	return nil
+}
+
+// nes20RomSize reads a rom size from its lsb byte and its msb nibble. an msb
+// of $F means the lsb holds an exponent and a multiplier instead
+func nes20RomSize(lsb uint8, msb uint8, unit int) (int, error) {
+	if msb == 0x0F {
+		exponent := lsb >> 2
+		if exponent > nes20MaxSizeExponent {
+			return 0, errors.New("rom size in header is too large")
+		}
+		multiplier := int(lsb&0x03)*2 + 1
+		return (1 << exponent) * multiplier, nil
+	}
+	return (int(msb)<<8 | int(lsb)) * unit, nil
+}
+
+// nes20RamSize reads a ram size given as a shift count, 0 means none
+func nes20RamSize(shift uint8) int {
+	if shift == 0 {
+		return 0
+	}
+	return 64 << shift
+}
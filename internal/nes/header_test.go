@@ -0,0 +1,141 @@
+package nes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/theaaronruss/nes-emulator/internal/mapper"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		data []uint8
+		want Header
+	}{
+		{
+			"ines",
+			[]uint8{'N', 'E', 'S', 0x1A, 2, 1, 0x11, 0x00, 0, 0, 0, 0, 0, 0, 0, 0},
+			Header{
+				Mapper:           1,
+				ProgramRomSize:   32768,
+				CharacterRomSize: 8192,
+				ProgramRamSize:   8192,
+				Mirroring:        mapper.MirrorVertical,
+			},
+		},
+		{
+			"ines with battery and chr ram",
+			[]uint8{'N', 'E', 'S', 0x1A, 8, 0, 0x22, 0x40, 2, 0, 0, 0, 0, 0, 0, 0},
+			Header{
+				Mapper:           0x42,
+				ProgramRomSize:   131072,
+				ProgramNvramSize: 16384,
+				CharacterRamSize: 8192,
+				Mirroring:        mapper.MirrorHorizontal,
+				Battery:          true,
+			},
+		},
+		{
+			"ines with a name written over the end",
+			[]uint8{'N', 'E', 'S', 0x1A, 1, 1, 0x40, 'D', 'i', 's', 'k', 'D', 'u', 'd', 'e', '!'},
+			Header{
+				Mapper:           4,
+				ProgramRomSize:   16384,
+				CharacterRomSize: 8192,
+				ProgramRamSize:   8192,
+				Mirroring:        mapper.MirrorHorizontal,
+			},
+		},
+		{
+			"nes 2.0",
+			[]uint8{'N', 'E', 'S', 0x1A, 0x02, 0x01, 0x4A, 0x58, 0x31, 0x10, 0x70, 0x07, 0x01, 0x00, 0x00, 0x01},
+			Header{
+				Nes20:            true,
+				Mapper:           0x154,
+				Submapper:        3,
+				ProgramRomSize:   32768,
+				CharacterRomSize: 0x101 * 8192,
+				ProgramNvramSize: 8192,
+				CharacterRamSize: 8192,
+				Mirroring:        mapper.MirrorFourScreen,
+				FourScreen:       true,
+				Battery:          true,
+				Timing:           TimingPal,
+				ExpansionDevice:  1,
+			},
+		},
+		{
+			"nes 2.0 exponent size",
+			[]uint8{'N', 'E', 'S', 0x1A, 0x4D, 0x00, 0x00, 0x08, 0x00, 0x0F, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			Header{
+				Nes20:          true,
+				ProgramRomSize: (1 << 19) * 3,
+				Mirroring:      mapper.MirrorHorizontal,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header, err := parseHeader(test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header != test.want {
+				t.Errorf("got %+v\nwant %+v", header, test.want)
+			}
+		})
+	}
+}
+
+func TestParseHeaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []uint8
+	}{
+		{"short", []uint8{'N', 'E', 'S', 0x1A, 1, 1}},
+		{"bad magic", []uint8{'N', 'E', 'Z', 0x1A, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"exponent too large", []uint8{'N', 'E', 'S', 0x1A, 0xFC, 0x00, 0x00, 0x08, 0x00, 0x0F, 0, 0, 0, 0, 0, 0}},
+		{"chr exponent too large", []uint8{'N', 'E', 'S', 0x1A, 0x01, 0x7F, 0x00, 0x08, 0x00, 0xF0, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseHeader(test.data)
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestNewCartridgeSizeCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []uint8
+		size    int
+		wantErr bool
+	}{
+		{"exact", []uint8{'N', 'E', 'S', 0x1A, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 16384 + 8192, false},
+		{"trainer", []uint8{'N', 'E', 'S', 0x1A, 1, 1, 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 512 + 16384 + 8192, false},
+		{"truncated", []uint8{'N', 'E', 'S', 0x1A, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 16384 + 8192, true},
+		{"missing trainer", []uint8{'N', 'E', 'S', 0x1A, 1, 1, 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 16384 + 8192, true},
+		{"huge nes 2.0 size", []uint8{'N', 'E', 'S', 0x1A, 0x78, 0x00, 0x00, 0x08, 0x00, 0x0F, 0, 0, 0, 0, 0, 0}, 16384, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.nes")
+			err := os.WriteFile(path, append(test.header, make([]uint8, test.size)...), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = NewCartridge(path)
+			if test.wantErr && err == nil {
+				t.Error("expected an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
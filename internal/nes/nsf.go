@@ -0,0 +1,331 @@
+package nes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	nsfHeaderSize       int    = 128
+	nsfBankSize         int    = 4096
+	nsfBankCount        int    = 8
+	nsfRamSize          int    = 8192
+	nsfDefaultPlaySpeed uint16 = 16639
+)
+
+// addresses
+const (
+	nsfDriverAddr       uint16 = 0x4100
+	nsfDriverAddrEnd    uint16 = 0x4102
+	nsfBankRegStartAddr uint16 = 0x5FF8
+	nsfBankRegEndAddr   uint16 = 0x5FFF
+	nsfRamStartAddr     uint16 = 0x6000
+	nsfRamEndAddr       uint16 = 0x7FFF
+	nsfRomStartAddr     uint16 = 0x8000
+)
+
+// the player sits in this loop whenever init or play have returned
+var nsfDriver = [3]uint8{0x4C, uint8(nsfDriverAddr & 0xFF), uint8(nsfDriverAddr >> 8)}
+
+type Nsf struct {
+	Title       string
+	Artist      string
+	Copyright   string
+	TrackTitles []string
+	SongCount   int
+	StartSong   int
+
+	loadAddr     uint16
+	initAddr     uint16
+	playAddr     uint16
+	playSpeed    uint16
+	bankInit     [nsfBankCount]uint8
+	bankswitched bool
+	data         []uint8
+}
+
+func NewNsf(filePath string) (*Nsf, error) {
+	const errorMessage = "failed to read nsf file: %w"
+
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf(errorMessage, err)
+	}
+
+	nsf := &Nsf{}
+	switch {
+	case bytes.HasPrefix(contents, []byte("NESM\x1A")):
+		err = nsf.parseNsf(contents)
+	case bytes.HasPrefix(contents, []byte("NSFE")):
+		err = nsf.parseNsfe(contents)
+	default:
+		err = errors.New("not an nsf or nsfe file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf(errorMessage, err)
+	}
+
+	err = nsf.validate()
+	if err != nil {
+		return nil, fmt.Errorf(errorMessage, err)
+	}
+
+	if nsf.playSpeed == 0 {
+		nsf.playSpeed = nsfDefaultPlaySpeed
+	}
+	if nsf.StartSong < 0 || nsf.StartSong >= nsf.SongCount {
+		nsf.StartSong = 0
+	}
+	nsf.layoutBanks()
+	return nsf, nil
+}
+
+func (nsf *Nsf) parseNsf(contents []uint8) error {
+	if len(contents) < nsfHeaderSize {
+		return errors.New("unexpected end of file")
+	}
+	header := contents[:nsfHeaderSize]
+
+	nsf.SongCount = int(header[6])
+	nsf.StartSong = int(header[7]) - 1
+	nsf.loadAddr = binary.LittleEndian.Uint16(header[8:])
+	nsf.initAddr = binary.LittleEndian.Uint16(header[10:])
+	nsf.playAddr = binary.LittleEndian.Uint16(header[12:])
+	nsf.Title = nsfString(header[14:46])
+	nsf.Artist = nsfString(header[46:78])
+	nsf.Copyright = nsfString(header[78:110])
+	nsf.playSpeed = binary.LittleEndian.Uint16(header[110:])
+	copy(nsf.bankInit[:], header[112:120])
+	nsf.data = contents[nsfHeaderSize:]
+
+	for _, bank := range nsf.bankInit {
+		if bank != 0 {
+			nsf.bankswitched = true
+		}
+	}
+	return nil
+}
+
+// parseNsfe reads the chunk based nsfe format. chunks with an upper case
+// first letter are required to be understood, the rest are optional
+func (nsf *Nsf) parseNsfe(contents []uint8) error {
+	contents = contents[4:]
+	foundInfo := false
+	foundData := false
+	for {
+		if len(contents) < 8 {
+			return errors.New("unexpected end of file")
+		}
+		length := binary.LittleEndian.Uint32(contents)
+		id := string(contents[4:8])
+		contents = contents[8:]
+		if uint32(len(contents)) < length {
+			return errors.New("unexpected end of file")
+		}
+		chunk := contents[:length]
+		contents = contents[length:]
+
+		switch id {
+		case "INFO":
+			if len(chunk) < 8 {
+				return errors.New("invalid INFO chunk")
+			}
+			nsf.loadAddr = binary.LittleEndian.Uint16(chunk[0:])
+			nsf.initAddr = binary.LittleEndian.Uint16(chunk[2:])
+			nsf.playAddr = binary.LittleEndian.Uint16(chunk[4:])
+			// the song count and starting song are optional
+			nsf.SongCount = 1
+			nsf.StartSong = 0
+			if len(chunk) > 8 {
+				nsf.SongCount = int(chunk[8])
+			}
+			if len(chunk) > 9 {
+				nsf.StartSong = int(chunk[9])
+			}
+			foundInfo = true
+		case "DATA":
+			nsf.data = chunk
+			foundData = true
+		case "BANK":
+			copy(nsf.bankInit[:], chunk)
+			nsf.bankswitched = true
+		case "RATE":
+			if len(chunk) >= 2 {
+				nsf.playSpeed = binary.LittleEndian.Uint16(chunk)
+			}
+		case "auth":
+			fields := nsfStrings(chunk)
+			for len(fields) < 3 {
+				fields = append(fields, "")
+			}
+			nsf.Title = fields[0]
+			nsf.Artist = fields[1]
+			nsf.Copyright = fields[2]
+		case "tlbl":
+			nsf.TrackTitles = nsfStrings(chunk)
+		case "NEND":
+			if !foundInfo || !foundData {
+				return errors.New("missing INFO or DATA chunk")
+			}
+			return nil
+		default:
+			if id[0] >= 'A' && id[0] <= 'Z' {
+				return fmt.Errorf("unsupported required chunk %q", id)
+			}
+		}
+	}
+}
+
+func (nsf *Nsf) validate() error {
+	if len(nsf.data) == 0 {
+		return errors.New("no song data")
+	}
+	if nsf.SongCount < 1 {
+		return errors.New("no songs")
+	}
+	if !nsf.bankswitched && nsf.loadAddr < nsfRomStartAddr {
+		return errors.New("load address is outside of rom")
+	}
+	return nil
+}
+
+// layoutBanks pads the song data so that it can be split into 4k banks. files
+// without bankswitching are loaded at their load address into a flat 32k space
+func (nsf *Nsf) layoutBanks() {
+	var padding int
+	if nsf.bankswitched {
+		padding = int(nsf.loadAddr & 0x0FFF)
+	} else {
+		padding = int(nsf.loadAddr - nsfRomStartAddr)
+		for i := range nsf.bankInit {
+			nsf.bankInit[i] = uint8(i)
+		}
+	}
+
+	size := padding + len(nsf.data)
+	size += (nsfBankSize - size%nsfBankSize) % nsfBankSize
+	data := make([]uint8, size)
+	copy(data[padding:], nsf.data)
+	nsf.data = data
+}
+
+func (nsf *Nsf) bankCount() int {
+	return len(nsf.data) / nsfBankSize
+}
+
+func nsfString(field []uint8) string {
+	if end := bytes.IndexByte(field, 0); end >= 0 {
+		field = field[:end]
+	}
+	return string(field)
+}
+
+func nsfStrings(chunk []uint8) []string {
+	fields := strings.Split(string(chunk), "\x00")
+	if len(fields) > 0 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	return fields
+}
+
+// nsfPlayer drives an nsf file's init and play routines with the cpu in place
+// of a game. between calls the cpu is parked in a small driver loop outside of
+// the song's address space
+type nsfPlayer struct {
+	sys        *System
+	nsf        *Nsf
+	song       int
+	ram        [nsfRamSize]uint8
+	banks      [nsfBankCount]int
+	playPeriod float64
+	playTimer  float64
+}
+
+func newNsfPlayer(sys *System, nsf *Nsf) *nsfPlayer {
+	return &nsfPlayer{
+		sys:        sys,
+		nsf:        nsf,
+		playPeriod: float64(nsf.playSpeed) * cpuClockRate / 1000000,
+	}
+}
+
+func (player *nsfPlayer) playSong(song int) {
+	player.song = song
+
+	for i := range player.sys.cpuRam {
+		player.sys.cpuRam[i] = 0
+	}
+	for i := range player.ram {
+		player.ram[i] = 0
+	}
+	for addr := apuChannelStartAddr; addr <= apuChannelEndAddr; addr++ {
+		player.sys.write(addr, 0)
+	}
+	player.sys.write(apuStatus, 0x00)
+	player.sys.write(apuStatus, 0x0F)
+	player.sys.write(apuFrameCounter, 0x40)
+	for i, bank := range player.nsf.bankInit {
+		player.writeBank(i, bank)
+	}
+
+	cpu := player.sys.cpu
+	cpu.abortInstruction()
+	cpu.a = uint8(song)
+	cpu.x = 0
+	cpu.y = 0
+	cpu.sp = initialStackPointer
+	cpu.status = initialStatus
+	cpu.call(player.nsf.initAddr, nsfDriverAddr)
+	player.playTimer = 0
+}
+
+func (player *nsfPlayer) clock() {
+	player.playTimer++
+	if player.playTimer < player.playPeriod {
+		return
+	}
+
+	// a play routine that is still running when the next one is due just
+	// causes the next call to be late, like it would on a real player
+	cpu := player.sys.cpu
+	if !cpu.atInstructionBoundary() || cpu.pc < nsfDriverAddr ||
+		cpu.pc > nsfDriverAddrEnd {
+		return
+	}
+	player.playTimer -= player.playPeriod
+	cpu.call(player.nsf.playAddr, nsfDriverAddr)
+}
+
+func (player *nsfPlayer) writeBank(index int, bank uint8) {
+	player.banks[index] = int(bank) % player.nsf.bankCount()
+}
+
+func (player *nsfPlayer) read(addr uint16) (uint8, bool) {
+	switch {
+	case addr >= nsfDriverAddr && addr <= nsfDriverAddrEnd:
+		return nsfDriver[addr-nsfDriverAddr], true
+	case addr >= nsfRamStartAddr && addr <= nsfRamEndAddr:
+		return player.ram[addr-nsfRamStartAddr], true
+	case addr >= nsfRomStartAddr:
+		offset := int(addr - nsfRomStartAddr)
+		bank := player.banks[offset/nsfBankSize]
+		return player.nsf.data[bank*nsfBankSize+offset%nsfBankSize], true
+	}
+	return 0, false
+}
+
+func (player *nsfPlayer) write(addr uint16, data uint8) bool {
+	switch {
+	case addr >= nsfBankRegStartAddr && addr <= nsfBankRegEndAddr:
+		player.writeBank(int(addr-nsfBankRegStartAddr), data)
+		return true
+	case addr >= nsfRamStartAddr && addr <= nsfRamEndAddr:
+		player.ram[addr-nsfRamStartAddr] = data
+		return true
+	}
+	return false
+}
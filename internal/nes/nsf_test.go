@@ -0,0 +1,87 @@
+package nes
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func nsfeChunk(id string, data ...uint8) []uint8 {
+	chunk := binary.LittleEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, id...)
+	return append(chunk, data...)
+}
+
+func nsfeFile(chunks ...[]uint8) []uint8 {
+	contents := []uint8("NSFE")
+	for _, chunk := range chunks {
+		contents = append(contents, chunk...)
+	}
+	return contents
+}
+
+func TestParseNsfe(t *testing.T) {
+	info := []uint8{0x00, 0x80, 0x03, 0x80, 0x06, 0x80, 0x00, 0x00}
+	data := nsfeChunk("DATA", 0x60)
+	end := nsfeChunk("NEND")
+
+	tests := []struct {
+		name      string
+		contents  []uint8
+		songCount int
+		startSong int
+		wantErr   bool
+	}{
+		{"info without song count", nsfeFile(nsfeChunk("INFO", info...), data, end), 1, 0, false},
+		{"info with song count", nsfeFile(nsfeChunk("INFO", append(info, 5)...), data, end), 5, 0, false},
+		{"info with start song", nsfeFile(nsfeChunk("INFO", append(info, 5, 2)...), data, end), 5, 2, false},
+		{"short info", nsfeFile(nsfeChunk("INFO", info[:7]...), data, end), 0, 0, true},
+		{"missing data", nsfeFile(nsfeChunk("INFO", info...), end), 0, 0, true},
+		{"unknown required chunk", nsfeFile(nsfeChunk("INFO", info...), data, nsfeChunk("ABCD"), end), 0, 0, true},
+		{"unknown optional chunk", nsfeFile(nsfeChunk("INFO", info...), data, nsfeChunk("abcd", 1), end), 1, 0, false},
+		{"truncated chunk", nsfeFile(nsfeChunk("INFO", info...))[:10], 0, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nsf := &Nsf{}
+			err := nsf.parseNsfe(test.contents)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nsf.SongCount != test.songCount || nsf.StartSong != test.startSong {
+				t.Errorf("got %d songs starting at %d, want %d starting at %d",
+					nsf.SongCount, nsf.StartSong, test.songCount, test.startSong)
+			}
+			if nsf.loadAddr != 0x8000 || nsf.initAddr != 0x8003 || nsf.playAddr != 0x8006 {
+				t.Errorf("wrong addresses %04X %04X %04X", nsf.loadAddr, nsf.initAddr, nsf.playAddr)
+			}
+		})
+	}
+}
+
+func TestParseNsfeStrings(t *testing.T) {
+	info := []uint8{0x00, 0x80, 0x03, 0x80, 0x06, 0x80, 0x00, 0x00, 2}
+	contents := nsfeFile(
+		nsfeChunk("INFO", info...),
+		nsfeChunk("DATA", 0x60),
+		nsfeChunk("auth", []uint8("Title\x00Artist\x00")...),
+		nsfeChunk("tlbl", []uint8("One\x00Two\x00")...),
+		nsfeChunk("NEND"),
+	)
+	nsf := &Nsf{}
+	err := nsf.parseNsfe(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nsf.Title != "Title" || nsf.Artist != "Artist" || nsf.Copyright != "" {
+		t.Errorf("got %q %q %q", nsf.Title, nsf.Artist, nsf.Copyright)
+	}
+	if len(nsf.TrackTitles) != 2 || nsf.TrackTitles[0] != "One" || nsf.TrackTitles[1] != "Two" {
+		t.Errorf("got track titles %q", nsf.TrackTitles)
+	}
+}
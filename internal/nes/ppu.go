@@ -1,14 +1,20 @@
 package nes
 
+import "github.com/theaaronruss/nes-emulator/internal/mapper"
+
 const (
 	FrameWidth       float64 = 256
 	FrameHeight      float64 = 240
+	FrameRate        float64 = cpuClockRate * 3 / (341*262 - 0.5)
 	incrementHor     uint16  = 1
 	incrementVer     uint16  = 32
 	paletteMemSize   int     = 32
 	nameTableMemSize int     = 2048
 	oamMemSize       int     = 256
 	nameTableSize    uint16  = 0x0400
+
+	// each bit of the io latch fades to 0 about 600ms after it was last set
+	ioLatchDecayFrames int = 36
 )
 
 // ppuctrl bit masks
@@ -32,8 +38,12 @@ const (
 	vblankBitMask         uint8 = 0x80
 	spriteHitBitMask      uint8 = 0x40
 	spriteOverflowBitMask uint8 = 0x20
+	ppuStatusBitMask      uint8 = 0xE0
 )
 
+// oam attribute bits that aren't stored
+const oamAttributeUnusedBitMask uint8 = 0x1C
+
 // vram bit masks
 const (
 	nameTableXBitMask uint16 = 0x0400
@@ -47,9 +57,13 @@ const (
 const (
 	cartridgeAddrEnd   uint16 = 0x1FFF
 	nameTableAddrStart uint16 = 0x2000
-	nameTableAddrEnd   uint16 = 0x2FFF
+	nameTableAddrEnd   uint16 = 0x3EFF
 	paletteAddrStart   uint16 = 0x3F00
 	paletteAddrEnd     uint16 = 0x3FFF
+	ppuAddrMask        uint16 = 0x3FFF
+
+	// $3000-$3EFF mirrors the name tables at $2000-$2EFF
+	nameTableMirrorBitMask uint16 = 0x1000
 )
 
 type ppu struct {
@@ -64,6 +78,11 @@ type ppu struct {
 	spriteCount  int
 	dataBuffer   uint8
 
+	// ioLatch is what the cpu reads from the ppu's write only registers,
+	// which is the last value put on the bus between them
+	ioLatch      uint8
+	ioLatchDecay [8]int
+
 	cycle          int
 	scanLine       int
 	oddFrame       bool
@@ -101,6 +120,7 @@ func NewPpu(sys *System) *ppu {
 		sys:             sys,
 		frameBuffer:     make([]uint8, int(FrameWidth)*int(FrameHeight)*4),
 		incrementAmount: 1,
+		spriteHeight:    8,
 	}
 	ppu.clearSecondOamMem()
 	return ppu
@@ -129,7 +149,10 @@ func (ppu *ppu) Clock() {
 		switch (ppu.cycle - 1) % 8 {
 		case 0:
 			ppu.loadIntoShifters()
-			ppu.fetchTileId()
+			// the name table read at dot 257 is part of the sprite fetches
+			if ppu.cycle != 257 {
+				ppu.fetchTileId()
+			}
 		case 2:
 			ppu.fetchTileAttribute()
 		case 4:
@@ -141,7 +164,7 @@ func (ppu *ppu) Clock() {
 		}
 	}
 
-	if ppu.bgEnabled && ppu.scanLine < 240 || ppu.scanLine == 261 {
+	if ppu.bgEnabled && (ppu.scanLine < 240 || ppu.scanLine == 261) {
 		if ppu.cycle == 256 {
 			ppu.incrementFineY()
 		}
@@ -151,7 +174,9 @@ func (ppu *ppu) Clock() {
 			ppu.loadXIntoVram()
 		}
 
-		if ppu.cycle == 338 || ppu.cycle == 340 {
+		// the next tile's id is read at the end of the line and again at dot
+		// 1, which is how the mmc5 spots the start of a scan line
+		if ppu.cycle == 1 || ppu.cycle == 338 || ppu.cycle == 340 {
 			ppu.fetchTileId()
 		}
 	}
@@ -161,8 +186,25 @@ func (ppu *ppu) Clock() {
 		ppu.loadYIntoVram()
 	}
 
-	if ppu.fgEnabled && ppu.scanLine < 240 && ppu.cycle == 0 {
-		ppu.spriteEvaluation()
+	// sprites for the next scan line are evaluated and fetched during dots
+	// 257-320 whenever rendering is on, which is when mappers like the mmc3
+	// watch the sprite pattern table addresses go by
+	if isRenderingEnabled && (ppu.scanLine < 240 || ppu.scanLine == 261) &&
+		ppu.cycle >= 257 && ppu.cycle <= 320 {
+		slot := (ppu.cycle - 257) / 8
+		switch (ppu.cycle - 257) % 8 {
+		case 0:
+			if slot == 0 {
+				ppu.spriteEvaluation()
+			}
+			ppu.fetchGarbageNameTable()
+		case 2:
+			ppu.fetchGarbageNameTable()
+		case 4:
+			ppu.fetchSpriteLow(slot)
+		case 6:
+			ppu.fetchSpriteHigh(slot)
+		}
 	}
 
 	// draw visible pixels
@@ -219,6 +261,7 @@ func (ppu *ppu) Clock() {
 
 	if ppu.scanLine == 241 && ppu.cycle == 1 {
 		ppu.vblank = true
+		ppu.decayIoLatch()
 		if ppu.vblankNmiEnable {
 			ppu.sys.cpu.Nmi()
 		}
@@ -239,15 +282,20 @@ func (ppu *ppu) Clock() {
 	}
 }
 
+// spriteEvaluation fills secondary oam with the sprites that are on the next
+// scan line. nothing is evaluated on the pre-render line so no sprites are
+// drawn on the first line
 func (ppu *ppu) spriteEvaluation() {
 	ppu.clearSecondOamMem()
+	if ppu.scanLine == 261 {
+		return
+	}
+
 	for spriteIndex := range len(ppu.oamMem) / 4 {
 		spriteY := int(ppu.oamMem[spriteIndex*4])
-		if ppu.scanLine >= spriteY+1 && ppu.scanLine < spriteY+9 {
+		if ppu.scanLine >= spriteY && ppu.scanLine < spriteY+ppu.spriteHeight {
 			if ppu.spriteCount < 8 {
-				patternRow := ppu.scanLine - spriteY - 1
 				ppu.copyToSecondOamMem(spriteIndex)
-				ppu.loadIntoForegroundShifters(spriteIndex, patternRow)
 				ppu.spriteCount++
 			} else {
 				ppu.spriteOverflow = true
@@ -263,28 +311,61 @@ func (ppu *ppu) copyToSecondOamMem(spriteIndex int) {
 	}
 }
 
-func (ppu *ppu) loadIntoForegroundShifters(spriteIndex int, patternRow int) {
-	tileId := int(ppu.oamMem[spriteIndex*4+1])
-	flipVer := ppu.oamMem[spriteIndex*4+2]&0x80 > 0
-	flipHor := ppu.oamMem[spriteIndex*4+2]&0x40 == 0
+// spritePatternAddr is the address of the low pattern byte for the sprite in
+// the given secondary oam slot. empty slots still fetch tile $FF
+func (ppu *ppu) spritePatternAddr(slot int) uint16 {
+	if slot >= ppu.spriteCount {
+		if ppu.spriteHeight == 16 {
+			return 0x1FF0
+		}
+		return ppu.fgPatternAddr + 0x0FF0
+	}
+
+	spriteY := int(ppu.secondOamMem[slot*4])
+	tileId := uint16(ppu.secondOamMem[slot*4+1])
+	flipVer := ppu.secondOamMem[slot*4+2]&0x80 > 0
+	patternRow := ppu.scanLine - spriteY
 	if flipVer {
-		patternRow = 7 - patternRow
+		patternRow = ppu.spriteHeight - 1 - patternRow
 	}
-	lowAddr := ppu.fgPatternAddr + (uint16(tileId) * 16) + uint16(patternRow)
-	highAddr := ppu.fgPatternAddr + (uint16(tileId) * 16) + uint16(patternRow) + 8
-	low := ppu.internalRead(lowAddr)
-	high := ppu.internalRead(highAddr)
-	if flipHor {
-		low = (low&0xF0)>>4 | (low&0x0F)<<4
-		low = (low&0xCC)>>2 | (low&0x33)<<2
-		low = (low&0xAA)>>1 | (low&0x55)<<1
 
-		high = (high&0xF0)>>4 | (high&0x0F)<<4
-		high = (high&0xCC)>>2 | (high&0x33)<<2
-		high = (high&0xAA)>>1 | (high&0x55)<<1
+	// tall sprites pick their pattern table with bit 0 of the tile id
+	patternAddr := ppu.fgPatternAddr
+	if ppu.spriteHeight == 16 {
+		patternAddr = (tileId & 0x01) * 0x1000
+		tileId &= 0xFE
+		if patternRow >= 8 {
+			tileId++
+			patternRow -= 8
+		}
+	}
+	return patternAddr + tileId*16 + uint16(patternRow)
+}
+
+func (ppu *ppu) fetchSpriteLow(slot int) {
+	low := ppu.internalRead(ppu.spritePatternAddr(slot))
+	ppu.fgPatternLsbShifters[slot] = ppu.spritePattern(slot, low)
+}
+
+func (ppu *ppu) fetchSpriteHigh(slot int) {
+	high := ppu.internalRead(ppu.spritePatternAddr(slot) + 8)
+	ppu.fgPatternMsbShifters[slot] = ppu.spritePattern(slot, high)
+}
+
+// spritePattern reverses the pattern bits so that they can be shifted out
+// starting from the lowest bit, unless the sprite is flipped horizontally
+func (ppu *ppu) spritePattern(slot int, data uint8) uint8 {
+	if slot >= ppu.spriteCount {
+		return 0
+	}
+
+	flipHor := ppu.secondOamMem[slot*4+2]&0x40 == 0
+	if flipHor {
+		data = (data&0xF0)>>4 | (data&0x0F)<<4
+		data = (data&0xCC)>>2 | (data&0x33)<<2
+		data = (data&0xAA)>>1 | (data&0x55)<<1
 	}
-	ppu.fgPatternLsbShifters[ppu.spriteCount] = low
-	ppu.fgPatternMsbShifters[ppu.spriteCount] = high
+	return data
 }
 
 func (ppu *ppu) getBackgroundPaletteIndex() int {
@@ -423,6 +504,12 @@ func (ppu *ppu) fetchTileId() {
 	ppu.bgTileId = ppu.internalRead(addr)
 }
 
+// fetchGarbageNameTable does the name table reads the ppu makes while it is
+// fetching sprites, which some mappers watch for
+func (ppu *ppu) fetchGarbageNameTable() {
+	ppu.internalRead(0x2000 | (ppu.vramAddr & 0x0FFF))
+}
+
 func (ppu *ppu) fetchTileAttribute() {
 	addr := 0x23C0 | (ppu.vramAddr & 0x0C00) | ((ppu.vramAddr >> 4) & 0x38) |
 		((ppu.vramAddr >> 2) & 0x07)
@@ -463,6 +550,29 @@ func (ppu *ppu) getColorFromPalette(paletteIndex int, colorIndex int) *color {
 	return &colors[colorCode]
 }
 
+// refreshIoLatch puts the bits of data picked by mask in the io latch and
+// stops them from fading for a while
+func (ppu *ppu) refreshIoLatch(data uint8, mask uint8) {
+	ppu.ioLatch = ppu.ioLatch&^mask | data&mask
+	for bit := range len(ppu.ioLatchDecay) {
+		if mask&(1<<bit) > 0 {
+			ppu.ioLatchDecay[bit] = ioLatchDecayFrames
+		}
+	}
+}
+
+func (ppu *ppu) decayIoLatch() {
+	for bit := range len(ppu.ioLatchDecay) {
+		if ppu.ioLatchDecay[bit] == 0 {
+			continue
+		}
+		ppu.ioLatchDecay[bit]--
+		if ppu.ioLatchDecay[bit] == 0 {
+			ppu.ioLatch &^= 1 << bit
+		}
+	}
+}
+
 func (ppu *ppu) readPpuStatus() uint8 {
 	var status uint8
 
@@ -479,17 +589,36 @@ func (ppu *ppu) readPpuStatus() uint8 {
 	}
 
 	ppu.writeToggle = false
-	return status
+
+	// the low bits aren't driven and come from the io latch
+	ppu.refreshIoLatch(status, ppuStatusBitMask)
+	return ppu.ioLatch
 }
 
 func (ppu *ppu) readOamData() uint8 {
-	return 0
+	data := ppu.oamMem[ppu.oamAddr]
+	if ppu.oamAddr%4 == 2 {
+		data &^= oamAttributeUnusedBitMask
+	}
+	ppu.refreshIoLatch(data, 0xFF)
+	return data
 }
 
 func (ppu *ppu) readPpuData() uint8 {
-	data := ppu.dataBuffer
-	ppu.dataBuffer = ppu.internalRead(ppu.vramAddr)
+	addr := ppu.vramAddr & ppuAddrMask
 	ppu.vramAddr += ppu.incrementAmount
+
+	// palette reads don't go through the buffer, which gets the name table
+	// byte under the palette instead. the palette is only 6 bits wide
+	if addr >= paletteAddrStart {
+		ppu.dataBuffer = ppu.internalRead(addr - nameTableMirrorBitMask)
+		ppu.refreshIoLatch(ppu.internalRead(addr), 0x3F)
+		return ppu.ioLatch
+	}
+
+	data := ppu.dataBuffer
+	ppu.dataBuffer = ppu.internalRead(addr)
+	ppu.refreshIoLatch(data, 0xFF)
 	return data
 }
 
@@ -550,6 +679,7 @@ func (ppu *ppu) writeOamAddr(data uint8) {
 
 func (ppu *ppu) writeOamData(data uint8) {
 	ppu.oamMem[ppu.oamAddr] = data
+	ppu.oamAddr++
 }
 
 func (ppu *ppu) writePpuScroll(data uint8) {
@@ -578,6 +708,7 @@ func (ppu *ppu) writePpuAddr(data uint8) {
 		ppu.tempAddr &= 0xFF00
 		ppu.tempAddr |= uint16(data)
 		ppu.vramAddr = ppu.tempAddr
+		ppu.watchAddr(ppu.vramAddr)
 	}
 	ppu.writeToggle = !ppu.writeToggle
 }
@@ -587,25 +718,18 @@ func (ppu *ppu) writePpuData(data uint8) {
 	ppu.vramAddr += ppu.incrementAmount
 }
 
-func (ppu *ppu) writeOamDma(data uint8) {
-	for i := range len(ppu.oamMem) {
-		addr := uint16(data)<<8 + uint16(i)
-		ppu.oamMem[i] = ppu.sys.read(addr)
-	}
-}
-
 func (ppu *ppu) internalRead(addr uint16) uint8 {
+	addr &= ppuAddrMask
+	ppu.watchAddr(addr)
 	switch {
 	case addr <= cartridgeAddrEnd:
-		return ppu.sys.cartridge.ReadCharacterData(addr)
-	case addr >= nameTableAddrStart && addr <= nameTableAddrEnd:
-		var nameTableAddr uint16
-		if ppu.sys.cartridge.HasHorizontalNameTableMirroring() {
-			nameTableAddr = ppu.translateHorizontalNameTableAddr(addr - nameTableAddrStart)
-		} else {
-			nameTableAddr = ppu.translateVerticalNameTableAddr(addr - nameTableAddrStart)
+		// nsf files have no pattern data
+		if ppu.sys.cartridge == nil {
+			return 0
 		}
-		return ppu.nameTableMem[nameTableAddr]
+		return ppu.sys.cartridge.ReadCharacterData(addr, ppu.nameTableMem[:])
+	case addr >= nameTableAddrStart && addr <= nameTableAddrEnd:
+		return ppu.readNameTable(addr &^ nameTableMirrorBitMask)
 	case addr >= paletteAddrStart && addr <= paletteAddrEnd:
 		paletteAddr := (addr - paletteAddrStart) & 0x1F
 		if paletteAddr >= 0x10 && paletteAddr%4 == 0 {
@@ -617,15 +741,15 @@ func (ppu *ppu) internalRead(addr uint16) uint8 {
 }
 
 func (ppu *ppu) internalWrite(addr uint16, data uint8) {
+	addr &= ppuAddrMask
+	ppu.watchAddr(addr)
 	switch {
-	case addr >= nameTableAddrStart && addr <= nameTableAddrEnd:
-		var nameTableAddr uint16
-		if ppu.sys.cartridge.HasHorizontalNameTableMirroring() {
-			nameTableAddr = ppu.translateHorizontalNameTableAddr(addr - nameTableAddrStart)
-		} else {
-			nameTableAddr = ppu.translateVerticalNameTableAddr(addr - nameTableAddrStart)
+	case addr <= cartridgeAddrEnd:
+		if ppu.sys.cartridge != nil {
+			ppu.sys.cartridge.WriteCharacterData(addr, data, ppu.nameTableMem[:])
 		}
-		ppu.nameTableMem[nameTableAddr] = data
+	case addr >= nameTableAddrStart && addr <= nameTableAddrEnd:
+		ppu.writeNameTable(addr&^nameTableMirrorBitMask, data)
 	case addr >= paletteAddrStart && addr <= paletteAddrEnd:
 		paletteAddr := (addr - paletteAddrStart) & 0x1F
 		if paletteAddr >= 0x10 && paletteAddr%4 == 0 {
@@ -635,7 +759,63 @@ func (ppu *ppu) internalWrite(addr uint16, data uint8) {
 	}
 }
 
-func (ppu *ppu) translateHorizontalNameTableAddr(addr uint16) uint16 {
+func (ppu *ppu) watchAddr(addr uint16) {
+	if ppu.sys.cartridge != nil {
+		ppu.sys.cartridge.WatchPpuAddr(addr)
+	}
+}
+
+func (ppu *ppu) nameTableMirroring() mapper.Mirroring {
+	// nsf files have no cartridge to say
+	if ppu.sys.cartridge == nil {
+		return mapper.MirrorHorizontal
+	}
+	return ppu.sys.cartridge.NameTableMirroring()
+}
+
+func (ppu *ppu) readNameTable(addr uint16) uint8 {
+	mirroring := ppu.nameTableMirroring()
+	if mirroring == mapper.MirrorMapperControlled {
+		return ppu.sys.cartridge.ReadNameTable(addr, ppu.nameTableMem[:])
+	}
+	return *ppu.nameTableByte(addr, mirroring)
+}
+
+func (ppu *ppu) writeNameTable(addr uint16, data uint8) {
+	mirroring := ppu.nameTableMirroring()
+	if mirroring == mapper.MirrorMapperControlled {
+		ppu.sys.cartridge.WriteNameTable(addr, data, ppu.nameTableMem[:])
+		return
+	}
+	*ppu.nameTableByte(addr, mirroring) = data
+}
+
+// nameTableByte finds the byte of vram behind a name table address, which is
+// in the cartridge's vram for the last two name tables of four screen boards
+func (ppu *ppu) nameTableByte(addr uint16, mirroring mapper.Mirroring) *uint8 {
+	index := int(ppu.translateNameTableAddr(addr, mirroring))
+	if index >= nameTableMemSize {
+		return &ppu.sys.cartridge.NameTableRam()[index-nameTableMemSize]
+	}
+	return &ppu.nameTableMem[index]
+}
+
+func (ppu *ppu) translateNameTableAddr(addr uint16, mirroring mapper.Mirroring) uint16 {
+	addr -= nameTableAddrStart
+	switch mirroring {
+	case mapper.MirrorVertical:
+		return ppu.translateVerticalNameTableAddr(addr)
+	case mapper.MirrorSingleLower:
+		return addr % nameTableSize
+	case mapper.MirrorSingleUpper:
+		return nameTableSize + addr%nameTableSize
+	case mapper.MirrorFourScreen:
+		return addr % (4 * nameTableSize)
+	}
+	return ppu.translateHorizontalNameTableAddr(addr)
+}
+
+func (ppu *ppu) translateVerticalNameTableAddr(addr uint16) uint16 {
 	nameTableIndex := addr / nameTableSize
 	offset := addr % nameTableSize
 	if nameTableIndex == 0 || nameTableIndex == 2 {
@@ -644,7 +824,7 @@ func (ppu *ppu) translateHorizontalNameTableAddr(addr uint16) uint16 {
 	return nameTableSize + offset
 }
 
-func (ppu *ppu) translateVerticalNameTableAddr(addr uint16) uint16 {
+func (ppu *ppu) translateHorizontalNameTableAddr(addr uint16) uint16 {
 	nameTableIndex := addr / nameTableSize
 	offset := addr % nameTableSize
 	if nameTableIndex == 0 || nameTableIndex == 1 {
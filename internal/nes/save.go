@@ -0,0 +1,111 @@
+package nes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// savePath is where the save file for a rom or disk image goes, the same path
+// with a .sav extension
+func savePath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sav"
+}
+
+// loadSave fills battery backed prg ram and chr ram from the save file if
+// there is one
+func (cartridge *Cartridge) loadSave() error {
+	if !cartridge.header.Battery {
+		return nil
+	}
+
+	data, err := os.ReadFile(cartridge.savePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read save file: %w", err)
+	}
+	n := copy(cartridge.board.ProgramRam, data)
+	copy(cartridge.characterNvram(), data[n:])
+	cartridge.savedData = cartridge.batteryData()
+	return nil
+}
+
+// characterNvram is the battery backed part of chr ram, which nes 2.0 headers
+// can ask for. it goes after the part that isn't battery backed
+func (cartridge *Cartridge) characterNvram() []uint8 {
+	if !cartridge.board.CharacterRam {
+		return nil
+	}
+	characterData := cartridge.board.CharacterData
+	return characterData[len(characterData)-cartridge.header.CharacterNvramSize:]
+}
+
+// batteryData is prg ram followed by battery backed chr ram, which is how
+// they are stored in the save file
+func (cartridge *Cartridge) batteryData() []uint8 {
+	return slices.Concat(cartridge.board.ProgramRam, cartridge.characterNvram())
+}
+
+// saveData returns what belongs in the save file, which is battery backed prg
+// ram or the sides of a disk. it is nil when there is nothing to save
+func (cartridge *Cartridge) saveData() []uint8 {
+	switch {
+	case cartridge.fds != nil:
+		if !cartridge.fds.Dirty() {
+			return nil
+		}
+		return bytes.Join(cartridge.fds.DiskSides(), nil)
+	case cartridge.header.Battery:
+		return cartridge.batteryData()
+	}
+	return nil
+}
+
+// Save writes what the game has saved to the save file if it has changed
+// since the last time. it is safe to call often
+func (cartridge *Cartridge) Save() error {
+	data := cartridge.saveData()
+	if data == nil || bytes.Equal(data, cartridge.savedData) {
+		return nil
+	}
+
+	err := writeFileAtomic(cartridge.savePath, data)
+	if err != nil {
+		return fmt.Errorf("failed to write save file: %w", err)
+	}
+	cartridge.savedData = data
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file next to path and renames it over
+// path once it is safely on disk, so a crash leaves either the old file or
+// the new one
+func writeFileAtomic(path string, data []uint8) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+
+	err = file.Chmod(0644)
+	if err == nil {
+		_, err = file.Write(data)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package nes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSavePath(t *testing.T) {
+	tests := []struct {
+		romPath string
+		want    string
+	}{
+		{"game.nes", "game.sav"},
+		{"roms/game.fds", "roms/game.sav"},
+		{"roms.d/game", "roms.d/game.sav"},
+		{"game.v1.nes", "game.v1.sav"},
+	}
+	for _, test := range tests {
+		if got := savePath(test.romPath); got != test.want {
+			t.Errorf("savePath(%q) = %q, want %q", test.romPath, got, test.want)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []uint8
+		data     []uint8
+	}{
+		{"new file", nil, []uint8{1, 2, 3}},
+		{"replace", []uint8{9, 9, 9, 9, 9}, []uint8{4, 5}},
+		{"empty", []uint8{9}, []uint8{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "game.sav")
+			if test.existing != nil {
+				err := os.WriteFile(path, test.existing, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := writeFileAtomic(path, test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != string(test.data) {
+				t.Errorf("file holds %v, want %v", data, test.data)
+			}
+
+			// the temporary file shouldn't be left behind
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("%d files in the directory, want 1", len(entries))
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicMissingDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "game.sav")
+	if err := writeFileAtomic(path, []uint8{1}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSaveCharacterNvram(t *testing.T) {
+	// nes 2.0 with a battery, 8k of prg nvram, 8k of chr ram and 8k of chr
+	// nvram
+	rom := make([]uint8, headerSize+16384)
+	copy(rom, []uint8{'N', 'E', 'S', 0x1A, 1, 0, 0x02, 0x08, 0, 0, 0x70, 0x77})
+	path := filepath.Join(t.TempDir(), "game.nes")
+	err := os.WriteFile(path, rom, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cartridge, err := NewCartridge(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cartridge.board.ProgramRam[0] = 0x11
+	cartridge.board.CharacterData[0] = 0x22
+	cartridge.board.CharacterData[8192] = 0x33
+	err = cartridge.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cartridge, err = NewCartridge(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := cartridge.board.ProgramRam[0]; data != 0x11 {
+		t.Errorf("$%02X in prg ram, want $11", data)
+	}
+	if data := cartridge.board.CharacterData[0]; data != 0x00 {
+		t.Errorf("$%02X in chr ram without a battery, want $00", data)
+	}
+	if data := cartridge.board.CharacterData[8192]; data != 0x33 {
+		t.Errorf("$%02X in chr nvram, want $33", data)
+	}
+}
@@ -9,6 +9,21 @@ const (
 	cpuRamStartAddr uint16 = 0x0000
 	cpuRamEndAddr   uint16 = 0x07FF
 	cpuRamSize      uint16 = cpuRamEndAddr - cpuRamStartAddr + 1
+
+	// the 2k of ram and the 8 ppu registers are repeated up to these
+	// addresses since the console doesn't decode every address line
+	cpuRamMirrorEndAddr      uint16 = 0x1FFF
+	ppuRegisterMirrorEndAddr uint16 = 0x3FFF
+	ppuRegisterCount         uint16 = 8
+
+	cartridgeStartAddr uint16 = 0x4020
+)
+
+// bits of reads from $4015-$4017 that nothing drives, so they keep what was
+// last on the data bus
+const (
+	apuStatusOpenBusBitMask  uint8 = 0x20
+	controllerOpenBusBitMask uint8 = 0xE0
 )
 
 // ppu registers
@@ -24,6 +39,14 @@ const (
 	oamDma    uint16 = 0x4014
 )
 
+// apu registers
+const (
+	apuChannelStartAddr uint16 = 0x4000
+	apuChannelEndAddr   uint16 = 0x4013
+	apuStatus           uint16 = 0x4015
+	apuFrameCounter     uint16 = 0x4017
+)
+
 // controller buttons
 const (
 	btnA      = 1 << iota
@@ -39,10 +62,18 @@ const (
 type System struct {
 	cpu            *cpu
 	ppu            *ppu
+	apu            *apu
 	cpuRam         [cpuRamSize]uint8
 	controllerData uint8
+	dataBus        uint8
 	win            *opengl.Window
 	cartridge      *Cartridge
+	nsf            *nsfPlayer
+	audioSink      AudioSink
+	resampler      *resampler
+	recordSink     AudioSink
+	recordSampler  *resampler
+	channelTaps    [AudioChannelCount]*channelTap
 
 	ppuClocks int
 }
@@ -53,72 +84,254 @@ func NewSystem(win *opengl.Window, cartridge *Cartridge) *System {
 		cartridge: cartridge,
 	}
 	sys.ppu = NewPpu(sys)
+	sys.apu = NewApu(sys)
+	sys.cpu = NewCpu(sys)
+	return sys
+}
+
+func NewNsfSystem(win *opengl.Window, nsf *Nsf) *System {
+	sys := &System{
+		win: win,
+	}
+	sys.ppu = NewPpu(sys)
+	sys.apu = NewApu(sys)
+	sys.nsf = newNsfPlayer(sys, nsf)
 	sys.cpu = NewCpu(sys)
+	sys.nsf.playSong(nsf.StartSong)
 	return sys
 }
 
+func (sys *System) NsfSongCount() int {
+	if sys.nsf == nil {
+		return 0
+	}
+	return sys.nsf.nsf.SongCount
+}
+
+func (sys *System) NsfSong() int {
+	if sys.nsf == nil {
+		return 0
+	}
+	return sys.nsf.song
+}
+
+func (sys *System) PlayNsfSong(song int) {
+	if sys.nsf == nil || song < 0 || song >= sys.nsf.nsf.SongCount {
+		return
+	}
+	sys.nsf.playSong(song)
+}
+
+// CpuHalted reports whether the cpu has run a jam opcode, and where. the ppu
+// and apu keep running but the game won't do anything else
+func (sys *System) CpuHalted() (uint16, bool) {
+	return sys.cpu.pc, sys.cpu.halted
+}
+
 func (sys *System) FrameBuffer() []uint8 {
 	return sys.ppu.frameBuffer
 }
 
+func (sys *System) SetAudioSink(sink AudioSink) {
+	sys.audioSink = sink
+	sys.resampler = nil
+	if sink != nil {
+		sys.resampler = newResampler(sink.SampleRate())
+	}
+}
+
+// SetRecordSink sends the mixed output to sink through a resampler of its own
+// that SetAudioRateAdjust never changes, so recording the same input always
+// gives the same samples. a nil sink stops recording
+func (sys *System) SetRecordSink(sink AudioSink) {
+	sys.recordSink = sink
+	sys.recordSampler = nil
+	if sink != nil {
+		sys.recordSampler = newResampler(sink.SampleRate())
+	}
+}
+
+func (sys *System) SetAudioRateAdjust(factor float64) {
+	if sys.resampler != nil {
+		sys.resampler.setRateAdjust(factor)
+	}
+}
+
+func (sys *System) SetChannelMuted(channel AudioChannel, muted bool) {
+	sys.apu.muted[channel] = muted
+}
+
+func (sys *System) ChannelMuted(channel AudioChannel) bool {
+	return sys.apu.muted[channel]
+}
+
+func (sys *System) SetChannelSolo(channel AudioChannel, solo bool) {
+	sys.apu.soloed[channel] = solo
+}
+
+func (sys *System) ChannelSolo(channel AudioChannel) bool {
+	return sys.apu.soloed[channel]
+}
+
+// SetChannelTap sends the output of a single channel to sink, before mixing
+// and regardless of it being muted. like SetRecordSink the tap has its own
+// resampler that isn't rate adjusted. a nil sink removes the tap
+func (sys *System) SetChannelTap(channel AudioChannel, sink AudioSink) {
+	sys.channelTaps[channel] = nil
+	if sink != nil {
+		sys.channelTaps[channel] = &channelTap{
+			sink:      sink,
+			resampler: newResampler(sink.SampleRate()),
+		}
+	}
+}
+
 func (sys *System) ClockFrame() {
 	for !sys.ppu.frameComplete {
 		sys.ppu.Clock()
 		sys.ppuClocks++
 		if sys.ppuClocks >= 3 {
 			sys.ppuClocks = 0
+			sys.apu.Clock()
+			if sys.nsf != nil {
+				sys.nsf.clock()
+			}
+			if sys.cartridge != nil {
+				sys.cartridge.ClockCpu()
+				if sys.cartridge.Irq() {
+					sys.cpu.Irq()
+				}
+			}
 			sys.cpu.Clock()
+			if sys.resampler != nil {
+				sys.resampler.push(sys.apu.sample)
+			}
+			if sys.recordSampler != nil {
+				sys.recordSampler.push(sys.apu.sample)
+			}
+			for channel, tap := range sys.channelTaps {
+				if tap != nil {
+					tap.resampler.push(sys.apu.channelSample(AudioChannel(channel)))
+				}
+			}
 		}
 	}
 	sys.ppu.frameComplete = false
+
+	if sys.resampler != nil {
+		sys.resampler.flush(sys.audioSink)
+	}
+	if sys.recordSampler != nil {
+		sys.recordSampler.flush(sys.recordSink)
+	}
+	for _, tap := range sys.channelTaps {
+		if tap != nil {
+			tap.resampler.flush(tap.sink)
+		}
+	}
 }
 
+// read reads from the cpu's bus. addresses that nothing responds to read
+// whatever was last on the data bus
 func (sys *System) read(addr uint16) uint8 {
+	// $4015 is read inside the cpu, so it doesn't change the data bus
+	if addr == apuStatus {
+		return sys.apu.readStatus() | sys.dataBus&apuStatusOpenBusBitMask
+	}
+	sys.dataBus = sys.readBus(addr)
+	return sys.dataBus
+}
+
+func (sys *System) readBus(addr uint16) uint8 {
+	if sys.nsf != nil {
+		if data, ok := sys.nsf.read(addr); ok {
+			return data
+		}
+	}
+
 	switch {
-	case addr <= 0x07FF:
-		return sys.cpuRam[addr]
-	case addr == ppuStatus:
-		return sys.ppu.readPpuStatus()
-	case addr == oamData:
-		return sys.ppu.readOamData()
-	case addr == ppuData:
-		return sys.ppu.readPpuData()
+	case addr <= cpuRamMirrorEndAddr:
+		return sys.cpuRam[addr%cpuRamSize]
+	case addr <= ppuRegisterMirrorEndAddr:
+		return sys.readPpuRegister(ppuCtrl + addr%ppuRegisterCount)
 	case addr == 0x4016:
 		data := sys.controllerData & 0x01
 		sys.controllerData >>= 1
 		sys.controllerData |= 0x80
-		return data
-	case sys.cartridge != nil && addr >= 0x8000:
-		return sys.cartridge.ReadProgramData(addr)
-	default:
-		return 0
+		return sys.dataBus&controllerOpenBusBitMask | data
+	case addr == 0x4017:
+		// there is no second controller
+		return sys.dataBus & controllerOpenBusBitMask
+	case sys.cartridge != nil && addr >= cartridgeStartAddr:
+		if data, ok := sys.cartridge.ReadProgramData(addr); ok {
+			return data
+		}
+	}
+	return sys.dataBus
+}
+
+func (sys *System) readPpuRegister(addr uint16) uint8 {
+	switch addr {
+	case ppuStatus:
+		return sys.ppu.readPpuStatus()
+	case oamData:
+		return sys.ppu.readOamData()
+	case ppuData:
+		return sys.ppu.readPpuData()
 	}
+	// the rest are write only, so reading them gives the ppu's io latch
+	return sys.ppu.ioLatch
 }
 
 func (sys *System) write(addr uint16, data uint8) {
+	sys.dataBus = data
+	if sys.nsf != nil && sys.nsf.write(addr, data) {
+		return
+	}
+
+	if sys.cartridge != nil && addr < cartridgeStartAddr {
+		sys.cartridge.WatchCpuWrite(addr, data)
+	}
+
 	switch {
-	case addr >= cpuRamStartAddr && addr <= cpuRamEndAddr:
-		sys.cpuRam[addr] = data
-	case addr == ppuCtrl:
+	case addr <= cpuRamMirrorEndAddr:
+		sys.cpuRam[addr%cpuRamSize] = data
+	case addr <= ppuRegisterMirrorEndAddr:
+		sys.writePpuRegister(ppuCtrl+addr%ppuRegisterCount, data)
+	case addr == oamDma:
+		sys.cpu.startOamDma(data)
+	case addr >= apuChannelStartAddr && addr <= apuChannelEndAddr:
+		sys.apu.writeChannel(addr, data)
+	case addr == apuStatus:
+		sys.apu.writeStatus(data)
+	case addr == 0x4016:
+		if data&0x01 > 0 {
+			sys.updateControllerInput()
+		}
+	case addr == apuFrameCounter:
+		sys.apu.writeFrameCounter(data)
+	case sys.cartridge != nil && addr >= cartridgeStartAddr:
+		sys.cartridge.WriteProgramData(addr, data)
+	}
+}
+
+func (sys *System) writePpuRegister(addr uint16, data uint8) {
+	sys.ppu.refreshIoLatch(data, 0xFF)
+	switch addr {
+	case ppuCtrl:
 		sys.ppu.writePpuCtrl(data)
-	case addr == ppuMask:
+	case ppuMask:
 		sys.ppu.writePpuMask(data)
-	case addr == oamAddr:
+	case oamAddr:
 		sys.ppu.writeOamAddr(data)
-	case addr == oamData:
+	case oamData:
 		sys.ppu.writeOamData(data)
-	case addr == ppuScroll:
+	case ppuScroll:
 		sys.ppu.writePpuScroll(data)
-	case addr == ppuAddr:
+	case ppuAddr:
 		sys.ppu.writePpuAddr(data)
-	case addr == ppuData:
+	case ppuData:
 		sys.ppu.writePpuData(data)
-	case addr == oamDma:
-		sys.ppu.writeOamDma(data)
-	case addr == 0x4016:
-		if data&0x01 > 0 {
-			sys.updateControllerInput()
-		}
 	}
 }
 
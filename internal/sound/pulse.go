@@ -0,0 +1,143 @@
+package sound
+
+// LengthTable is what length counters are loaded with, indexed by the top 5
+// bits of the value written to a channel's length register
+var LengthTable = [32]uint8{
+	10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+	12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+}
+
+var dutyTable = [4][8]uint8{
+	{0, 1, 0, 0, 0, 0, 0, 0},
+	{0, 1, 1, 0, 0, 0, 0, 0},
+	{0, 1, 1, 1, 1, 0, 0, 0},
+	{1, 0, 0, 1, 1, 1, 1, 1},
+}
+
+// MixPulses is the apu's nonlinear mix of the sum of its two pulse channels
+func MixPulses(sum float32) float32 {
+	if sum <= 0 {
+		return 0
+	}
+	return 95.88 / (8128/sum + 100)
+}
+
+// Envelope is the volume envelope of the pulse and noise channels, which
+// decays from 15 to 0 or outputs a constant volume
+type Envelope struct {
+	start    bool
+	loop     bool
+	constant bool
+	volume   uint8
+	divider  uint8
+	decay    uint8
+}
+
+// Write takes the loop, constant volume and volume bits from a channel's
+// control register
+func (env *Envelope) Write(data uint8) {
+	env.loop = data&0x20 > 0
+	env.constant = data&0x10 > 0
+	env.volume = data & 0x0F
+}
+
+// Restart makes the next clock start the decay over
+func (env *Envelope) Restart() {
+	env.start = true
+}
+
+func (env *Envelope) Clock() {
+	if env.start {
+		env.start = false
+		env.decay = 15
+		env.divider = env.volume
+		return
+	}
+
+	if env.divider > 0 {
+		env.divider--
+		return
+	}
+
+	env.divider = env.volume
+	if env.decay > 0 {
+		env.decay--
+	} else if env.loop {
+		env.decay = 15
+	}
+}
+
+func (env *Envelope) Output() uint8 {
+	if env.constant {
+		return env.volume
+	}
+	return env.decay
+}
+
+// Pulse is a pulse channel without a sweep unit. the apu adds its sweep on top
+// and the mmc5 uses it as is
+type Pulse struct {
+	Length      uint8
+	TimerPeriod uint16
+
+	enabled    bool
+	duty       uint8
+	dutyStep   uint8
+	timer      uint16
+	lengthHalt bool
+	env        Envelope
+}
+
+func (pulse *Pulse) WriteControl(data uint8) {
+	pulse.duty = data >> 6
+	pulse.lengthHalt = data&0x20 > 0
+	pulse.env.Write(data)
+}
+
+func (pulse *Pulse) WriteTimerLow(data uint8) {
+	pulse.TimerPeriod &= 0xFF00
+	pulse.TimerPeriod |= uint16(data)
+}
+
+func (pulse *Pulse) WriteTimerHigh(data uint8) {
+	pulse.TimerPeriod &= 0x00FF
+	pulse.TimerPeriod |= uint16(data&0x07) << 8
+	if pulse.enabled {
+		pulse.Length = LengthTable[data>>3]
+	}
+	pulse.dutyStep = 0
+	pulse.env.Restart()
+}
+
+func (pulse *Pulse) SetEnabled(enabled bool) {
+	pulse.enabled = enabled
+	if !enabled {
+		pulse.Length = 0
+	}
+}
+
+func (pulse *Pulse) ClockTimer() {
+	if pulse.timer == 0 {
+		pulse.timer = pulse.TimerPeriod
+		pulse.dutyStep = (pulse.dutyStep + 1) % 8
+	} else {
+		pulse.timer--
+	}
+}
+
+func (pulse *Pulse) ClockLength() {
+	if !pulse.lengthHalt && pulse.Length > 0 {
+		pulse.Length--
+	}
+}
+
+func (pulse *Pulse) ClockEnvelope() {
+	pulse.env.Clock()
+}
+
+func (pulse *Pulse) Output() uint8 {
+	if pulse.Length == 0 || dutyTable[pulse.duty][pulse.dutyStep] == 0 {
+		return 0
+	}
+	return pulse.env.Output()
+}
@@ -0,0 +1,63 @@
+package sound
+
+import "testing"
+
+func TestEnvelope(t *testing.T) {
+	tests := []struct {
+		name    string
+		control uint8
+		clocks  int
+		want    uint8
+	}{
+		{"constant", 0x17, 40, 7},
+		{"start", 0x00, 1, 15},
+		{"decay every clock", 0x00, 6, 10},
+		{"decay every other clock", 0x01, 7, 12},
+		{"stops at zero", 0x00, 40, 0},
+		{"loops", 0x20, 17, 15},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var env Envelope
+			env.Write(test.control)
+			env.Restart()
+			for range test.clocks {
+				env.Clock()
+			}
+			if output := env.Output(); output != test.want {
+				t.Errorf("output %d, want %d", output, test.want)
+			}
+		})
+	}
+}
+
+func TestPulse(t *testing.T) {
+	var pulse Pulse
+	pulse.WriteTimerHigh(0x08)
+	if pulse.Length != 0 {
+		t.Errorf("length %d loaded while disabled, want 0", pulse.Length)
+	}
+
+	pulse.SetEnabled(true)
+	pulse.WriteControl(0x9F)
+	pulse.WriteTimerLow(0x00)
+	pulse.WriteTimerHigh(0x08)
+	if pulse.Length != LengthTable[1] {
+		t.Errorf("length %d, want %d", pulse.Length, LengthTable[1])
+	}
+
+	// the 50% duty cycle is high for steps 1 to 4
+	var steps [8]uint8
+	for i := range steps {
+		steps[i] = pulse.Output()
+		pulse.ClockTimer()
+	}
+	if steps != [8]uint8{0, 15, 15, 15, 15, 0, 0, 0} {
+		t.Errorf("got duty steps %v", steps)
+	}
+
+	pulse.SetEnabled(false)
+	if pulse.Length != 0 || pulse.Output() != 0 {
+		t.Error("still playing after being disabled")
+	}
+}